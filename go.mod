@@ -8,12 +8,12 @@ require (
 	github.com/javiorfo/nilo v1.6.0
 	github.com/javiorfo/steams/v2 v2.0.0
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/crypto v0.32.0
+	golang.org/x/sys v0.29.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/crypto v0.32.0 // indirect
-	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
 )
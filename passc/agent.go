@@ -0,0 +1,485 @@
+package passc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/javiorfo/nilo"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	agentOpGet     = "get"
+	agentOpSet     = "set"
+	agentOpLock    = "lock"
+	agentOpStatus  = "status"
+	agentOpLockAll = "lockall"
+
+	agentDialTimeout  = time.Second
+	agentStartTimeout = 2 * time.Second
+	agentExpireTick   = time.Second
+)
+
+var agentRunningError = errors.New(passcAgentRunningErr)
+
+// storeKey is the derived key of a store together with the header values it was derived from
+type storeKey struct {
+	Key  []byte    `json:"key"`
+	Salt []byte    `json:"salt"`
+	KDF  KDFParams `json:"kdf"`
+}
+
+func (k *storeKey) wipe() {
+	for i := range k.Key {
+		k.Key[i] = 0
+	}
+}
+
+type agentRequest struct {
+	Op      string         `json:"op"`
+	Store   string         `json:"store,omitempty"`
+	Key     *storeKey      `json:"key,omitempty"`
+	Session *SessionConfig `json:"session,omitempty"`
+}
+
+type agentResponse struct {
+	Key    *storeKey    `json:"key,omitempty"`
+	Status *agentStatus `json:"status,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// agentStatus reports when the session locks. Zero times mean there is no such limit
+type agentStatus struct {
+	Unlocked    bool      `json:"unlocked"`
+	IdleLocksAt time.Time `json:"idleLocksAt"`
+	MaxLocksAt  time.Time `json:"maxLocksAt"`
+}
+
+type agentSession struct {
+	key       *storeKey
+	config    SessionConfig
+	createdAt time.Time
+	lastUsed  time.Time
+}
+
+func (s agentSession) status() agentStatus {
+	status := agentStatus{Unlocked: true}
+	if s.config.IdleTimeout > 0 {
+		status.IdleLocksAt = s.lastUsed.Add(time.Duration(s.config.IdleTimeout))
+	}
+	if s.config.MaxLifetime > 0 {
+		status.MaxLocksAt = s.createdAt.Add(time.Duration(s.config.MaxLifetime))
+	}
+	return status
+}
+
+func (s agentSession) isExpired(now time.Time) bool {
+	status := s.status()
+	return (!status.IdleLocksAt.IsZero() && !now.Before(status.IdleLocksAt)) ||
+		(!status.MaxLocksAt.IsZero() && !now.Before(status.MaxLocksAt))
+}
+
+// agent keeps the unlocked store keys in memory only, like ssh-agent does with private keys.
+// There is one agent per user and one session per canonical store path
+type agent struct {
+	mu       sync.Mutex
+	sessions map[string]*agentSession
+	now      func() time.Time
+}
+
+func newAgent() *agent {
+	return &agent{sessions: make(map[string]*agentSession), now: time.Now}
+}
+
+// runtimeDir is the private per-user directory holding the agent socket:
+// $XDG_RUNTIME_DIR/passc when available, otherwise $TMPDIR/passc-<uid>
+func runtimeDir() (string, error) {
+	var dir string
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" && filepath.IsAbs(xdg) {
+		dir = filepath.Join(xdg, passcAppCommandName)
+	} else {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d", passcAppCommandName, os.Getuid()))
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("creating runtime dir: %v", err)
+	}
+	if err := checkPrivateDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// checkPrivateDir refuses directories planted by someone else, since /tmp is shared
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("checking runtime dir: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("runtime dir %s is not a directory", dir)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("runtime dir %s is not owned by the current user", dir)
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(dir, 0700); err != nil {
+			return fmt.Errorf("securing runtime dir: %v", err)
+		}
+	}
+	return nil
+}
+
+func agentSocketPath() (string, error) {
+	dir, err := runtimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, passcAgentSocket), nil
+}
+
+// canonicalStorePath identifies a vault no matter how its path was written
+func canonicalStorePath(filePath string) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := filepath.EvalSymlinks(filepath.Dir(absPath))
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.Base(absPath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return filepath.Join(dir, filepath.Base(absPath)), nil
+		}
+		return "", err
+	}
+	return resolved, nil
+}
+
+func runAgent() error {
+	socketPath, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+
+	if conn, err := net.DialTimeout("unix", socketPath, agentDialTimeout); err == nil {
+		conn.Close()
+		return agentRunningError
+	}
+	os.Remove(socketPath)
+
+	// The socket must never be reachable by other users, not even for a moment
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", socketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %v", socketPath, err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	a := newAgent()
+	stop := make(chan struct{})
+	go a.expireLoop(stop)
+
+	a.serve(listener)
+	close(stop)
+	a.lock()
+	return nil
+}
+
+func (a *agent) expireLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(agentExpireTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			a.expire()
+			a.mu.Unlock()
+		}
+	}
+}
+
+// expire wipes every session that reached its idle or lifetime limit. Callers hold a.mu
+func (a *agent) expire() {
+	now := a.now()
+	for store, session := range a.sessions {
+		if session.isExpired(now) {
+			a.wipe(store)
+		}
+	}
+}
+
+func (a *agent) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go a.handleConn(conn)
+	}
+}
+
+func (a *agent) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentDialTimeout))
+
+	uid, err := peerUID(conn)
+	if err != nil || uid != os.Getuid() {
+		return
+	}
+
+	var request agentRequest
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		return
+	}
+	json.NewEncoder(conn).Encode(a.handle(request))
+}
+
+func (a *agent) handle(request agentRequest) agentResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.expire()
+
+	if request.Op != agentOpLockAll && request.Store == "" {
+		return agentResponse{Error: "missing store"}
+	}
+	session := a.sessions[request.Store]
+
+	switch request.Op {
+	case agentOpGet:
+		if session == nil {
+			return agentResponse{}
+		}
+		session.lastUsed = a.now()
+		// a copy, so wiping the session never races with encoding the response
+		key := *session.key
+		key.Key = append([]byte{}, key.Key...)
+		return agentResponse{Key: &key}
+	case agentOpSet:
+		if request.Key == nil {
+			return agentResponse{Error: "missing key"}
+		}
+		a.wipe(request.Store)
+		// best effort: keep the key out of swap
+		unix.Mlock(request.Key.Key)
+		now := a.now()
+		session = &agentSession{key: request.Key, createdAt: now, lastUsed: now}
+		if request.Session != nil {
+			session.config = *request.Session
+		}
+		a.sessions[request.Store] = session
+	case agentOpLock:
+		a.wipe(request.Store)
+	case agentOpLockAll:
+		for store := range a.sessions {
+			a.wipe(store)
+		}
+	case agentOpStatus:
+		if session == nil {
+			return agentResponse{Status: &agentStatus{}}
+		}
+		status := session.status()
+		return agentResponse{Status: &status}
+	default:
+		return agentResponse{Error: fmt.Sprintf("unknown operation %q", request.Op)}
+	}
+	return agentResponse{}
+}
+
+func (a *agent) lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for store := range a.sessions {
+		a.wipe(store)
+	}
+}
+
+func (a *agent) wipe(store string) {
+	if session, ok := a.sessions[store]; ok {
+		session.key.wipe()
+		unix.Munlock(session.key.Key)
+		delete(a.sessions, store)
+	}
+}
+
+// peerUID reads the uid of the process on the other side of the socket (SO_PEERCRED)
+func peerUID(conn net.Conn) (int, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, fmt.Errorf("not a unix socket")
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+	return int(cred.Uid), nil
+}
+
+func agentCall(socketPath string, request agentRequest) (*agentResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath, agentDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentDialTimeout))
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+
+	var response agentResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// agentStoreCall sends an operation about one store, identified by its canonical path
+func agentStoreCall(op, filePath string) (*agentResponse, error) {
+	socketPath, err := agentSocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := canonicalStorePath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("resolving store path: %v", err)
+	}
+
+	return agentCall(socketPath, agentRequest{Op: op, Store: store})
+}
+
+func isAgentDown(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func getAgentKey(filePath string) nilo.Option[storeKey] {
+	response, err := agentStoreCall(agentOpGet, filePath)
+	if err != nil || response.Key == nil {
+		return nilo.Nil[storeKey]()
+	}
+	return nilo.Value(*response.Key)
+}
+
+// setAgentKey hands the key to the agent, starting it in the background if needed
+func setAgentKey(filePath string, key storeKey, session SessionConfig) error {
+	socketPath, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+
+	store, err := canonicalStorePath(filePath)
+	if err != nil {
+		return fmt.Errorf("resolving store path: %v", err)
+	}
+
+	request := agentRequest{Op: agentOpSet, Store: store, Key: &key, Session: &session}
+	if _, err := agentCall(socketPath, request); err == nil {
+		return nil
+	}
+
+	if err := startAgent(socketPath); err != nil {
+		return err
+	}
+	_, err = agentCall(socketPath, request)
+	return err
+}
+
+func startAgent(socketPath string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding executable: %v", err)
+	}
+
+	cmd := exec.Command(executable, "agent")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting agent: %v", err)
+	}
+	cmd.Process.Release()
+
+	deadline := time.Now().Add(agentStartTimeout)
+	for time.Now().Before(deadline) {
+		if conn, err := net.DialTimeout("unix", socketPath, agentDialTimeout); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("starting agent: timeout waiting for %s", socketPath)
+}
+
+// getAgentStatus returns nil when the agent is not running
+func getAgentStatus(filePath string) (*agentStatus, error) {
+	response, err := agentStoreCall(agentOpStatus, filePath)
+	if err != nil {
+		if isAgentDown(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("asking agent status: %v", err)
+	}
+	return response.Status, nil
+}
+
+// lockSession makes the agent forget the key of one store. No agent running means no session
+func lockSession(filePath string) error {
+	if _, err := agentStoreCall(agentOpLock, filePath); err != nil && !isAgentDown(err) {
+		return fmt.Errorf("locking session: %v", err)
+	}
+	return nil
+}
+
+// lockAllSessions makes the agent forget the keys of every store of the current user
+func lockAllSessions() error {
+	socketPath, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+
+	if _, err := agentCall(socketPath, agentRequest{Op: agentOpLockAll}); err != nil && !isAgentDown(err) {
+		return fmt.Errorf("locking sessions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package passc
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testStore = "/home/user/.passcualito/store.passc"
+
+func startTestAgent(t *testing.T) (*agent, string) {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), passcAgentSocket)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	a := newAgent()
+	go a.serve(listener)
+	return a, socketPath
+}
+
+func TestAgentSession(t *testing.T) {
+	a, socketPath := startTestAgent(t)
+
+	response, err := agentCall(socketPath, agentRequest{Op: agentOpGet, Store: testStore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Key != nil {
+		t.Fatal("a new agent must not hold any key")
+	}
+
+	key := storeKey{Key: bytes.Repeat([]byte{7}, kdfKeySize), Salt: []byte("salt"), KDF: testKDFParams}
+	if _, err := agentCall(socketPath, agentRequest{Op: agentOpSet, Store: testStore, Key: &key}); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err = agentCall(socketPath, agentRequest{Op: agentOpGet, Store: testStore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Key == nil || !bytes.Equal(response.Key.Key, key.Key) || response.Key.KDF != key.KDF {
+		t.Fatalf("expected stored key, got %+v", response.Key)
+	}
+
+	a.mu.Lock()
+	stored := a.sessions[testStore].key.Key
+	a.mu.Unlock()
+
+	if _, err := agentCall(socketPath, agentRequest{Op: agentOpLock, Store: testStore}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, make([]byte, kdfKeySize)) {
+		t.Error("lock must wipe the key from memory")
+	}
+
+	response, err = agentCall(socketPath, agentRequest{Op: agentOpGet, Store: testStore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Key != nil {
+		t.Error("a locked agent must not return any key")
+	}
+
+	if _, err := agentCall(socketPath, agentRequest{Op: "dump", Store: testStore}); err == nil {
+		t.Error("unknown operations must be rejected")
+	}
+}
+
+func TestEncryptorUnlockedKey(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	withPassword := &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}
+	key, err := withPassword.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := withPassword.encryptText(`{"version":1,"entries":[{"name":"john","password":"1234","info":""}]}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// what a later command gets back from the agent: no master password at all
+	withKey := &Encryptor{FilePath: filePath, key: key}
+	if _, err := withKey.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	vault := &fileVault{encryptor: withKey}
+	if err := vault.Put(Data{Name: "jane", Password: "5678"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := (&fileVault{encryptor: withPassword}).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 entries, got %+v", items)
+	}
+
+	wrongKey := &Encryptor{FilePath: filePath, key: &storeKey{Key: make([]byte, kdfKeySize), Salt: key.Salt, KDF: key.KDF}}
+	if _, err := wrongKey.unlock(); err == nil {
+		t.Error("a key that does not match the store must be rejected")
+	}
+}
+
+func TestAgentSessionTimeouts(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	a := newAgent()
+	a.now = func() time.Time { return now }
+
+	session := SessionConfig{IdleTimeout: Duration(15 * time.Minute), MaxLifetime: Duration(8 * time.Hour)}
+	set := func() {
+		key := storeKey{Key: bytes.Repeat([]byte{7}, kdfKeySize), Salt: []byte("salt"), KDF: testKDFParams}
+		if response := a.handle(agentRequest{Op: agentOpSet, Store: testStore, Key: &key, Session: &session}); response.Error != "" {
+			t.Fatal(response.Error)
+		}
+	}
+	isUnlocked := func() bool {
+		return a.handle(agentRequest{Op: agentOpGet, Store: testStore}).Key != nil
+	}
+
+	set()
+	status := a.handle(agentRequest{Op: agentOpStatus, Store: testStore}).Status
+	if !status.Unlocked || !status.IdleLocksAt.Equal(now.Add(15*time.Minute)) || !status.MaxLocksAt.Equal(now.Add(8*time.Hour)) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// every get refreshes the idle timer, up to 16:50
+	for range 47 {
+		now = now.Add(10 * time.Minute)
+		if !isUnlocked() {
+			t.Fatalf("session must stay unlocked while in use, at %s", now)
+		}
+	}
+
+	now = time.Date(2026, 1, 1, 17, 0, 0, 0, time.UTC)
+	if isUnlocked() {
+		t.Error("session must lock after its maximum lifetime even when in use")
+	}
+
+	set()
+	stored := a.sessions[testStore].key.Key
+	now = now.Add(15 * time.Minute)
+	a.mu.Lock()
+	a.expire()
+	a.mu.Unlock()
+	if a.sessions[testStore] != nil || !bytes.Equal(stored, make([]byte, kdfKeySize)) {
+		t.Error("an idle session must be wiped")
+	}
+	if status := a.handle(agentRequest{Op: agentOpStatus, Store: testStore}).Status; status.Unlocked {
+		t.Error("status must report a locked vault")
+	}
+}
+
+func TestAgentSessionsPerStore(t *testing.T) {
+	a := newAgent()
+	work := "/home/user/work/store.passc"
+
+	for _, store := range []string{testStore, work} {
+		key := storeKey{Key: bytes.Repeat([]byte(store[:1]), kdfKeySize), Salt: []byte(store)}
+		if response := a.handle(agentRequest{Op: agentOpSet, Store: store, Key: &key}); response.Error != "" {
+			t.Fatal(response.Error)
+		}
+	}
+
+	if response := a.handle(agentRequest{Op: agentOpGet}); response.Error == "" {
+		t.Error("requests without a store must be rejected")
+	}
+
+	a.handle(agentRequest{Op: agentOpLock, Store: testStore})
+	if a.handle(agentRequest{Op: agentOpGet, Store: testStore}).Key != nil {
+		t.Error("locked store must not return a key")
+	}
+	if key := a.handle(agentRequest{Op: agentOpGet, Store: work}).Key; key == nil || string(key.Salt) != work {
+		t.Error("locking one store must keep the other one unlocked")
+	}
+
+	a.handle(agentRequest{Op: agentOpLockAll})
+	if len(a.sessions) != 0 {
+		t.Error("lock all must wipe every session")
+	}
+}
+
+func TestRuntimeDir(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", xdg)
+
+	dir, err := runtimeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(xdg, passcAppCommandName) {
+		t.Errorf("expected runtime dir inside XDG_RUNTIME_DIR, got %s", dir)
+	}
+
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runtimeDir(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("runtime dir must be private, got %v", info.Mode().Perm())
+	}
+
+	file := filepath.Join(xdg, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPrivateDir(file); err == nil {
+		t.Error("a file must not be accepted as runtime dir")
+	}
+}
+
+func TestCanonicalStorePath(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, passcStoreFile)
+	if err := os.WriteFile(storePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.passc")
+	if err := os.Symlink(storePath, link); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := canonicalStorePath(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{link, filepath.Join(dir, ".", "..", filepath.Base(dir), passcStoreFile)} {
+		actual, err := canonicalStorePath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, actual)
+		}
+	}
+}
+
+func TestAgentKeyOnEmptyStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	if err := os.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// passc init: the session starts on a store that is still empty
+	withPassword := &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}
+	key, err := withPassword.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// passc add later on with the agent key, and again after the last entry was removed
+	for range 2 {
+		withKey := &Encryptor{FilePath: filePath, key: key}
+		if _, err := withKey.unlock(); err != nil {
+			t.Fatal(err)
+		}
+		vault := &fileVault{encryptor: withKey}
+		if err := vault.Put(Data{Name: "jane", Password: "5678"}); err != nil {
+			t.Fatal(err)
+		}
+
+		reopened := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath}}
+		if items, err := reopened.List(); err != nil || len(items) != 1 {
+			t.Fatalf("the master password must open the store, got %+v %v", items, err)
+		}
+		if err := vault.Delete("jane"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// without a key nor a master password nothing is derived
+	if _, err := (&Encryptor{FilePath: filePath}).unlock(); !errors.Is(err, invalidPasswordError) {
+		t.Errorf("expected invalid password, got %v", err)
+	}
+	if err := (Encryptor{FilePath: filePath}).encryptText("{}"); !errors.Is(err, invalidPasswordError) {
+		t.Errorf("expected invalid password, got %v", err)
+	}
+}
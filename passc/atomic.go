@@ -0,0 +1,65 @@
+package passc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeTemp writes the content of a file being replaced. Tests swap it to simulate a crash or a full disk
+var writeTemp = func(file *os.File, data []byte) error {
+	_, err := file.Write(data)
+	return err
+}
+
+// writeFileAtomic replaces path with data so that readers (and a crash at any point)
+// see either the previous content or the new one, never a partial file.
+// The data goes to a temporary file in the same directory which is synced and renamed over path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %v", err)
+	}
+	tmpPath := file.Name()
+
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err := file.Chmod(perm); err != nil {
+		return fmt.Errorf("setting permissions: %v", err)
+	}
+	if err := writeTemp(file, data); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing %s: %v", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replacing %s: %v", path, err)
+	}
+	committed = true
+
+	return syncDir(dir)
+}
+
+// syncDir makes the renames done inside dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("opening directory: %v", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing directory: %v", err)
+	}
+	return nil
+}
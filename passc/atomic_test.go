@@ -0,0 +1,120 @@
+package passc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// failWrites makes writeFileAtomic write half of the data and then fail, like a full disk
+func failWrites(t *testing.T) {
+	original := writeTemp
+	writeTemp = func(file *os.File, data []byte) error {
+		if _, err := file.Write(data[:len(data)/2]); err != nil {
+			return err
+		}
+		return errors.New("no space left on device")
+	}
+	t.Cleanup(func() { writeTemp = original })
+}
+
+func expectOnlyFiles(t *testing.T, dir string, expected ...string) {
+	t.Helper()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected files %v, got %v", expected, names)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+
+	if err := writeFileAtomic(path, []byte("first"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "second" {
+		t.Errorf("expected second, got %q", content)
+	}
+	if stat, err := os.Stat(path); err != nil || stat.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600 permissions, got %v %v", stat.Mode(), err)
+	}
+
+	failWrites(t)
+	if err := writeFileAtomic(path, []byte("third, never written"), 0600); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if content, _ := os.ReadFile(path); string(content) != "second" {
+		t.Errorf("a failed write must keep the previous content, got %q", content)
+	}
+	expectOnlyFiles(t, dir, "file")
+}
+
+func TestFailedWriteKeepsVault(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, passcStoreFile)
+	vault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}}
+
+	john := Data{Name: "john", Password: "1234"}
+	if err := vault.Put(john); err != nil {
+		t.Fatal(err)
+	}
+	store, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failWrites(t)
+
+	mutations := map[string]func() error{
+		"put":    func() error { return vault.Put(Data{Name: "jane", Password: "5678"}) },
+		"edit":   func() error { return vault.Put(Data{Name: "john", Password: "changed"}) },
+		"rename": func() error { return vault.Rename("john", "johnny") },
+		"delete": func() error { return vault.Delete("john") },
+		"rekey": func() error {
+			return rekeyStore(filePath, "p4$$w0rd", "n3w-p4$$w0rd!", testKDFParams)
+		},
+	}
+	for name, mutate := range mutations {
+		t.Run(name, func(t *testing.T) {
+			if err := mutate(); err == nil {
+				t.Fatal("expected the mutation to fail")
+			}
+
+			current, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(current, store) {
+				t.Error("the store must be left untouched")
+			}
+
+			items, err := vault.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, []Data{john}) {
+				t.Errorf("expected the previous vault, got %+v", items)
+			}
+			expectOnlyFiles(t, dir, filepath.Base(backupDir(filePath)), passcStoreFile, passcStoreFile+".lock")
+		})
+	}
+}
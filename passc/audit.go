@@ -0,0 +1,206 @@
+package passc
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	checkBreached       = "breached"
+	checkReused         = "reused"
+	checkWeak           = "weak"
+	checkNameAsPassword = "name-as-password"
+	checkOld            = "old"
+	checkUnknownAge     = "unknown-age"
+	checkNoUsername     = "no-username"
+	checkNoURL          = "no-url"
+
+	severityNone   = "none"
+	severityInfo   = "info"
+	severityLow    = "low"
+	severityMedium = "medium"
+	severityHigh   = "high"
+)
+
+// auditChecks is also the order of the report for findings of the same severity
+var auditChecks = []string{checkBreached, checkReused, checkNameAsPassword, checkWeak, checkOld, checkUnknownAge, checkNoUsername, checkNoURL}
+
+// info findings are only reported, they never make passc audit fail
+var severityRanks = map[string]int{severityNone: 0, severityInfo: 1, severityLow: 2, severityMedium: 3, severityHigh: 4}
+
+// Finding is a problem found by passc audit in one entry, or in several for reused passwords
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity string   `json:"severity"`
+	Entries  []string `json:"entries"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type auditReport struct {
+	Entries  int       `json:"entries"`
+	Findings []Finding `json:"findings"`
+	// Failed tells if a finding reached the failOn severity
+	Failed bool `json:"failed"`
+}
+
+func validateSeverity(severity string) error {
+	if _, ok := severityRanks[severity]; !ok {
+		return fmt.Errorf("severity %q must be none, info, low, medium or high", severity)
+	}
+	return nil
+}
+
+func validateFailOn(severity string) error {
+	if _, ok := severityRanks[severity]; !ok || severity == severityInfo {
+		return fmt.Errorf("severity %q must be none, low, medium or high", severity)
+	}
+	return nil
+}
+
+func (c AuditConfig) validate() error {
+	if err := validateFailOn(c.FailOn); err != nil {
+		return fmt.Errorf("audit failOn: %v", err)
+	}
+	for check, severity := range c.Severities {
+		if !slices.Contains(auditChecks, check) {
+			return fmt.Errorf("audit severities: unknown check %q", check)
+		}
+		if err := validateSeverity(severity); err != nil {
+			return fmt.Errorf("audit severities: %v", err)
+		}
+	}
+	return nil
+}
+
+// auditEntries runs every check. Weak passwords are scored against minScore, old ones against
+// config.MaxAge and breached ones are looked up in breaches, when there is one
+func auditEntries(items []Data, config AuditConfig, minScore int, breaches breachDB, now time.Time) (auditReport, error) {
+	var findings []Finding
+	add := func(check, severity string, entries []string, detail string) {
+		if override, ok := config.Severities[check]; ok {
+			severity = override
+		}
+		if severity != severityNone {
+			findings = append(findings, Finding{Check: check, Severity: severity, Entries: entries, Detail: detail})
+		}
+	}
+
+	shared := make(map[string][]string)
+	for _, d := range items {
+		if d.Password != "" {
+			shared[d.Password] = append(shared[d.Password], d.Name)
+		}
+	}
+	for _, names := range shared {
+		if len(names) > 1 {
+			sort.Strings(names)
+			add(checkReused, severityHigh, names, fmt.Sprintf("%d entries share the same password", len(names)))
+		}
+	}
+
+	for _, d := range items {
+		entry := []string{d.Name}
+
+		if breaches != nil {
+			found, count, err := breaches.lookup(passwordHash(d.Password))
+			if err != nil {
+				return auditReport{}, err
+			}
+			if found {
+				add(checkBreached, severityHigh, entry, breachDetail(count))
+			}
+		}
+
+		if strings.EqualFold(d.Password, d.Name) || strings.EqualFold(d.Password, baseName(d.Name)) {
+			add(checkNameAsPassword, severityHigh, entry, "the password is the entry name")
+		} else if strength := estimateStrength(d.Password, d.Name, d.Username); strength.Score < minScore {
+			severity := severityMedium
+			if strength.Score <= 1 {
+				severity = severityHigh
+			}
+			detail := fmt.Sprintf("score %d/4, cracked in %s", strength.Score, strength.CrackTime())
+			if strength.Warning != "" {
+				detail += ". " + strength.Warning
+			}
+			add(checkWeak, severity, entry, detail)
+		}
+
+		if config.MaxAge > 0 {
+			changedAt := d.passwordChangedAt()
+			switch {
+			case changedAt.IsZero():
+				// entries imported or created before dates were kept, not necessarily old
+				add(checkUnknownAge, severityInfo, entry, "the entry was created before dates were kept")
+			case now.Sub(changedAt) > time.Duration(config.MaxAge):
+				add(checkOld, severityMedium, entry, fmt.Sprintf("not changed for %d days", int(now.Sub(changedAt).Hours()/24)))
+			}
+		}
+
+		if d.Username == "" {
+			add(checkNoUsername, severityLow, entry, "")
+		}
+		if len(d.URLs) == 0 {
+			add(checkNoURL, severityLow, entry, "")
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return severityRanks[a.Severity] > severityRanks[b.Severity]
+		}
+		if a.Check != b.Check {
+			return slices.Index(auditChecks, a.Check) < slices.Index(auditChecks, b.Check)
+		}
+		return a.Entries[0] < b.Entries[0]
+	})
+
+	report := auditReport{Entries: len(items), Findings: findings}
+	if findings == nil {
+		report.Findings = []Finding{}
+	}
+	if config.FailOn != severityNone {
+		for _, f := range findings {
+			if f.Severity != severityInfo && severityRanks[f.Severity] >= severityRanks[config.FailOn] {
+				report.Failed = true
+			}
+		}
+	}
+	return report, nil
+}
+
+// count returns how many findings have the severity
+func (r auditReport) count(severity string) int {
+	count := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (r auditReport) print() {
+	fmt.Printf(passcAuditTitle, r.Entries)
+	if len(r.Findings) == 0 {
+		fmt.Println(passcAuditCleanText)
+		return
+	}
+
+	fmt.Println("│")
+	for i, f := range r.Findings {
+		branch := "├──"
+		if i == len(r.Findings)-1 {
+			branch = "└──"
+		}
+		line := fmt.Sprintf("%s \033[1m%-6s\033[0m %-16s %s", branch, f.Severity, f.Check, strings.Join(f.Entries, ", "))
+		if f.Detail != "" {
+			line += " (" + f.Detail + ")"
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf(passcAuditSummaryText, len(r.Findings), r.count(severityHigh), r.count(severityMedium), r.count(severityLow), r.count(severityInfo))
+}
@@ -0,0 +1,100 @@
+package passc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditEntries(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-24 * time.Hour)
+	complete := func(d Data) Data {
+		d.Username = "john"
+		d.URLs = []string{"https://" + d.Name + ".com"}
+		d.CreatedAt = recent
+		return d
+	}
+
+	items := []Data{
+		complete(Data{Name: "github", Password: "Xk9$mQ2vLp3@wR7n"}),
+		complete(Data{Name: "gitlab", Password: "Xk9$mQ2vLp3@wR7n"}),
+		complete(Data{Name: "work/acme", Password: "Acme"}),
+		complete(Data{Name: "bank", Password: "password"}),
+		complete(Data{Name: "mail", Password: "correcthorsebatterystaple", History: []PasswordChange{{Password: "old", ChangedAt: now.Add(-400 * 24 * time.Hour)}}}),
+		{Name: "legacy", Password: "Yp4&nR8zQw2!kT6m"},
+	}
+	config := AuditConfig{MaxAge: Duration(365 * 24 * time.Hour), FailOn: severityHigh}
+
+	report, err := auditEntries(items, config, 3, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type key struct {
+		check    string
+		severity string
+		entries  string
+	}
+	var actual []key
+	for _, f := range report.Findings {
+		actual = append(actual, key{f.Check, f.Severity, f.Entries[0] + "+" + f.Entries[len(f.Entries)-1]})
+	}
+	expected := []key{
+		{checkReused, severityHigh, "github+gitlab"},
+		{checkNameAsPassword, severityHigh, "work/acme+work/acme"},
+		{checkWeak, severityHigh, "bank+bank"},
+		{checkOld, severityMedium, "mail+mail"},
+		{checkNoUsername, severityLow, "legacy+legacy"},
+		{checkNoURL, severityLow, "legacy+legacy"},
+		{checkUnknownAge, severityInfo, "legacy+legacy"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if !report.Failed || report.Entries != len(items) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// overridden severities move findings or hide them, and failOn follows them
+	config.Severities = map[string]string{checkReused: severityLow, checkNameAsPassword: severityNone, checkWeak: severityMedium}
+	config.MaxAge = 0
+	if report, err = auditEntries(items, config, 3, nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed {
+		t.Error("no finding is high anymore")
+	}
+	if report.count(severityMedium) != 1 || report.count(severityLow) != 3 {
+		t.Errorf("unexpected findings %+v", report.Findings)
+	}
+
+	if report, _ = auditEntries(items[:1], AuditConfig{FailOn: severityLow}, 3, nil, now); report.Failed || len(report.Findings) != 0 {
+		t.Errorf("expected a clean report, got %+v", report)
+	}
+
+	// an unknown age is reported but never fails, even with the lowest failOn
+	undated := complete(Data{Name: "undated", Password: "Yp4&nR8zQw2!kT6m"})
+	undated.CreatedAt = time.Time{}
+	report, _ = auditEntries([]Data{undated}, AuditConfig{MaxAge: Duration(time.Hour), FailOn: severityLow}, 3, nil, now)
+	if report.Failed || len(report.Findings) != 1 || report.Findings[0].Check != checkUnknownAge {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestAuditConfigValidate(t *testing.T) {
+	valid := AuditConfig{FailOn: severityMedium, Severities: map[string]string{checkNoURL: severityNone}}
+	if err := valid.validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, invalid := range []AuditConfig{
+		{FailOn: "critical"},
+		{FailOn: severityInfo},
+		{FailOn: severityHigh, Severities: map[string]string{"unknown": severityLow}},
+		{FailOn: severityHigh, Severities: map[string]string{checkOld: "urgent"}},
+	} {
+		if err := invalid.validate(); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupIDFormat names each generation after the time it was taken
+const backupIDFormat = "20060102-150405.000"
+
+var backupNotFoundError = errors.New(passcBackupNotFoundErr)
+
+// backup is one generation of a store, a full copy of the encrypted file
+type backup struct {
+	ID        string
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// listBackups returns the generations of the store, newest first
+func listBackups(filePath string) ([]backup, error) {
+	dir := backupDir(filePath)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading backups: %v", err)
+	}
+
+	var backups []backup
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file.Name(), filepath.Ext(passcStoreFile))
+		if !ok || file.IsDir() {
+			continue
+		}
+		createdAt, err := time.ParseInLocation(backupIDFormat, id, time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, fmt.Errorf("reading backups: %v", err)
+		}
+		backups = append(backups, backup{ID: id, Path: filepath.Join(dir, file.Name()), CreatedAt: createdAt, Size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+func findBackup(filePath, id string) (*backup, error) {
+	backups, err := listBackups(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", backupNotFoundError, id)
+}
+
+// makeBackUp copies the store into a new generation and prunes the ones out of retention.
+// An empty store has nothing worth keeping and returns emptyFile
+func makeBackUp(filePath string, retention BackupConfig) (*backup, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading store: %v", err)
+	}
+	if len(content) == 0 {
+		return nil, emptyFile
+	}
+
+	dir := backupDir(filePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating backups directory: %v", err)
+	}
+	if err := adoptLegacyBackup(filePath); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().Truncate(time.Millisecond)
+	var path string
+	for {
+		path = filepath.Join(dir, createdAt.Format(backupIDFormat)+filepath.Ext(passcStoreFile))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		createdAt = createdAt.Add(time.Millisecond)
+	}
+
+	if err := writeFileAtomic(path, content, 0600); err != nil {
+		return nil, err
+	}
+	if err := pruneBackups(filePath, retention, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &backup{ID: createdAt.Format(backupIDFormat), Path: path, CreatedAt: createdAt, Size: int64(len(content))}, nil
+}
+
+// adoptLegacyBackup moves the single store.bkp of older versions into the generations. One still in
+// the legacy layout is moved aside instead, like passc migrate does, so generations are always
+// in the current format
+func adoptLegacyBackup(filePath string) error {
+	legacyPath := legacyBackupFilePath(filePath)
+	stat, err := os.Stat(legacyPath)
+	if err != nil {
+		return nil
+	}
+	if stat.Size() == 0 {
+		return os.Remove(legacyPath)
+	}
+
+	legacy, err := isLegacyFile(legacyPath)
+	if err != nil {
+		return fmt.Errorf("reading legacy backup: %v", err)
+	}
+	if legacy {
+		if err := os.Rename(legacyPath, legacyFilePath(legacyPath, stat.ModTime())); err != nil {
+			return fmt.Errorf("moving legacy backup aside: %v", err)
+		}
+		return nil
+	}
+
+	id := stat.ModTime().Format(backupIDFormat)
+	if err := os.Rename(legacyPath, filepath.Join(backupDir(filePath), id+filepath.Ext(passcStoreFile))); err != nil {
+		return fmt.Errorf("moving legacy backup: %v", err)
+	}
+	return nil
+}
+
+// pruneBackups deletes the generations beyond the retention count or older than its max age
+func pruneBackups(filePath string, retention BackupConfig, now time.Time) error {
+	backups, err := listBackups(filePath)
+	if err != nil {
+		return err
+	}
+
+	for i, b := range backups {
+		if i == 0 {
+			continue
+		}
+		tooMany := retention.Keep > 0 && i >= retention.Keep
+		tooOld := retention.MaxAge > 0 && now.Sub(b.CreatedAt) > time.Duration(retention.MaxAge)
+		if tooMany || tooOld {
+			if err := os.Remove(b.Path); err != nil {
+				return fmt.Errorf("deleting backup %s: %v", b.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// restoreBackup replaces the store with a generation after checking it opens with the
+// encryptor's master password. The replaced store is kept as a new generation
+func restoreBackup(encryptor *Encryptor, id string, retention BackupConfig) (*backup, error) {
+	b, err := findBackup(encryptor.FilePath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	check := Encryptor{MasterPassword: encryptor.MasterPassword, FilePath: b.Path, key: encryptor.key}
+	if _, err := check.readEncryptedText(); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(b.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading backup: %v", err)
+	}
+
+	replaced, err := makeBackUp(encryptor.FilePath, retention)
+	if err != nil && !errors.Is(err, emptyFile) {
+		return nil, fmt.Errorf("backing up store: %v", err)
+	}
+
+	if err := writeFileAtomic(encryptor.FilePath, content, 0644); err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
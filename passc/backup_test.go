@@ -0,0 +1,234 @@
+package passc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackupGenerations(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	vault := &fileVault{
+		encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams},
+		retention: BackupConfig{Keep: 3},
+	}
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := vault.Put(Data{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := listBackups(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("expected 3 generations, got %d", len(backups))
+	}
+
+	// newest first, and each generation is the store right after one change
+	for i, expected := range []int{5, 4, 3} {
+		backupVault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: backups[i].Path}}
+		items, err := backupVault.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != expected {
+			t.Errorf("generation %s: expected %d entries, got %d", backups[i].ID, expected, len(items))
+		}
+	}
+}
+
+func TestMoveIsOneGeneration(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	vault := &fileVault{
+		encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams},
+		retention: BackupConfig{Keep: 3},
+	}
+
+	moves := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := vault.Put(Data{Name: "work/" + name}); err != nil {
+			t.Fatal(err)
+		}
+		moves["work/"+name] = "archive/" + name
+	}
+	if err := vault.Move(moves); err != nil {
+		t.Fatal(err)
+	}
+
+	// the generation before the move still has the whole folder in place
+	backups, err := listBackups(filePath)
+	if err != nil || len(backups) != 3 {
+		t.Fatalf("unexpected backups %+v %v", backups, err)
+	}
+	items, err := (&fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: backups[1].Path}}).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range items {
+		if !inFolder(d.Name, "work/") {
+			t.Errorf("unexpected entry %s before the move", d.Name)
+		}
+	}
+}
+
+func TestLegacyBackupKeptOutOfGenerations(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	legacyPath := legacyBackupFilePath(filePath)
+	legacy := writeLegacyStore(t, legacyPath, "p4$$w0rd", `{"name":"John","password":"1234","info":""}`)
+
+	// a generation adopted in the legacy layout by an older version
+	if err := os.MkdirAll(backupDir(filePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	adopted := filepath.Join(backupDir(filePath), "20200101-000000.000"+filepath.Ext(passcStoreFile))
+	if err := os.WriteFile(adopted, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}}
+	if err := vault.Put(Data{Name: "acme", Password: "0000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Errorf("a legacy store.bkp must not stay in place, got %v", err)
+	}
+	if matches, _ := filepath.Glob(legacyPath + ".legacy-*"); len(matches) != 1 {
+		t.Errorf("a legacy store.bkp must be moved aside, got %v", matches)
+	}
+	backups, err := listBackups(filePath)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("unexpected backups %+v %v", backups, err)
+	}
+
+	// passwd leaves the legacy generation alone instead of failing
+	if err := rekeyStore(filePath, "p4$$w0rd", "New-p4$$w0rd", testKDFParams); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(adopted); !bytes.Equal(content, legacy) {
+		t.Error("the legacy generation must be left untouched")
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	if err := os.MkdirAll(backupDir(filePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.Local)
+	var ids []string
+	for _, age := range []time.Duration{0, time.Hour, 48 * time.Hour, 72 * time.Hour, 30 * 24 * time.Hour} {
+		id := now.Add(-age).Format(backupIDFormat)
+		ids = append(ids, id)
+		if err := os.WriteFile(filepath.Join(backupDir(filePath), id+".passc"), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectIDs := func(expected ...string) {
+		t.Helper()
+		backups, err := listBackups(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []string
+		for _, b := range backups {
+			actual = append(actual, b.ID)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected %v, got %v", expected, actual)
+		}
+	}
+
+	if err := pruneBackups(filePath, BackupConfig{}, now); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(ids...)
+
+	if err := pruneBackups(filePath, BackupConfig{Keep: 4}, now); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(ids[:4]...)
+
+	if err := pruneBackups(filePath, BackupConfig{MaxAge: Duration(24 * time.Hour)}, now); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(ids[:2]...)
+
+	// the newest generation survives whatever its age
+	if err := pruneBackups(filePath, BackupConfig{MaxAge: Duration(time.Minute)}, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(ids[0])
+}
+
+func TestRestoreBackup(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	encryptor := &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}
+	vault := &fileVault{encryptor: encryptor}
+
+	john := Data{Name: "john", Password: "1234"}
+	if err := vault.Put(john); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := listBackups(filePath)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one generation, got %+v %v", backups, err)
+	}
+	good := backups[0]
+
+	// the bad edit
+	if err := vault.Delete("john"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := restoreBackup(encryptor, "19990101-000000.000", BackupConfig{}); !errors.Is(err, backupNotFoundError) {
+		t.Errorf("expected backup not found, got %v", err)
+	}
+
+	wrong := &Encryptor{MasterPassword: "wr0ng!", FilePath: filePath}
+	if _, err := restoreBackup(wrong, good.ID, BackupConfig{}); !errors.Is(err, invalidPasswordError) {
+		t.Errorf("expected invalid password, got %v", err)
+	}
+	if items, err := vault.List(); err != nil || len(items) != 0 {
+		t.Fatalf("a rejected restore must leave the store untouched, got %+v %v", items, err)
+	}
+
+	if err := os.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	replaced, err := restoreBackup(encryptor, good.ID, BackupConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced != nil {
+		t.Error("an empty store has nothing to keep")
+	}
+
+	items, err := vault.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(items, []Data{john}) {
+		t.Errorf("expected %+v, got %+v", []Data{john}, items)
+	}
+
+	if err := vault.Put(Data{Name: "jane"}); err != nil {
+		t.Fatal(err)
+	}
+	if replaced, err = restoreBackup(encryptor, good.ID, BackupConfig{}); err != nil || replaced == nil {
+		t.Fatalf("the replaced store must be kept as a new generation, got %+v %v", replaced, err)
+	}
+	kept := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: replaced.Path}}
+	if items, err := kept.List(); err != nil || len(items) != 2 {
+		t.Errorf("expected the replaced store with 2 entries, got %+v %v", items, err)
+	}
+}
@@ -0,0 +1,371 @@
+package passc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// The breached passwords come from the Pwned Passwords SHA-1 corpus downloaded to disk, ordered by
+// hash: one "HASH:COUNT" line per password. Passwords never leave the machine, only their hash is
+// looked up, either by binary search in that file or in a bloom filter built from it
+
+const (
+	bloomMagic      = "PASSCBF1"
+	bloomHeaderSize = len(bloomMagic) + 8 + 4 + 8
+	breachDBFile    = "breach.bloom"
+	// binary search stops when the range fits a few pages and reads it sequentially
+	hashFileWindow = 4096
+)
+
+var breachDBError = errors.New(passcBreachDBErr)
+
+// breachDB finds password hashes. count is 0 when the source does not keep how often a hash was seen
+type breachDB interface {
+	lookup(hash [sha1.Size]byte) (found bool, count int, err error)
+	Close() error
+}
+
+func passwordHash(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+// defaultBreachDBPath is where passc breachdb build writes the bloom filter when no path is given
+func defaultBreachDBPath() (string, error) {
+	dir, err := passcDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, breachDBFile), nil
+}
+
+// configuredBreachDB opens the breachDB of config.json or the default bloom filter. It returns
+// nil when none is set up, the check is optional
+func configuredBreachDB(config *Config) (breachDB, error) {
+	path := config.BreachDB
+	if path == "" {
+		defaultPath, err := defaultBreachDBPath()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+	return openBreachDB(path)
+}
+
+// openBreachDB opens a bloom filter built by passc breachdb build or a sorted hash file
+func openBreachDB(path string) (breachDB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", breachDBError, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %v", breachDBError, err)
+	}
+
+	magic := make([]byte, len(bloomMagic))
+	if _, err := file.ReadAt(magic, 0); err == nil && string(magic) == bloomMagic {
+		bloom, err := openBloomFilter(file, info.Size())
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return bloom, nil
+	}
+	return &hashFile{file: file, size: info.Size()}, nil
+}
+
+// hashFile is the Pwned Passwords file ordered by hash. Lines are "HASH:COUNT" or just "HASH"
+type hashFile struct {
+	file *os.File
+	size int64
+}
+
+func (h *hashFile) Close() error {
+	return h.file.Close()
+}
+
+func (h *hashFile) lookup(hash [sha1.Size]byte) (bool, int, error) {
+	target := strings.ToUpper(hex.EncodeToString(hash[:]))
+
+	// lo always starts a line before the target one, or the file
+	lo, hi := int64(0), h.size
+	for hi-lo > hashFileWindow {
+		mid := lo + (hi-lo)/2
+		line, err := h.lineAfter(mid)
+		if err != nil {
+			return false, 0, err
+		}
+		if line == nil || lineHash(line) >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	reader := bufio.NewReader(h.sectionFrom(lo))
+	if lo > 0 {
+		// the section starts on the byte before lo: drop the line lo is part of
+		if _, err := reader.ReadSlice('\n'); err != nil {
+			return false, 0, nil
+		}
+	}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			hash := lineHash(line)
+			if hash == target {
+				count, _ := strconv.Atoi(lineCount(line))
+				return true, count, nil
+			}
+			if hash > target {
+				return false, 0, nil
+			}
+		}
+		if err == io.EOF {
+			return false, 0, nil
+		}
+		if err != nil {
+			return false, 0, fmt.Errorf("%w: %v", breachDBError, err)
+		}
+	}
+}
+
+// sectionFrom reads the file from the byte before offset, so a line starting exactly at offset
+// is found after the first newline
+func (h *hashFile) sectionFrom(offset int64) io.Reader {
+	if offset > 0 {
+		offset--
+	}
+	return io.NewSectionReader(h.file, offset, h.size-offset)
+}
+
+// lineAfter returns the first line starting at or after offset, nil at the end of the file
+func (h *hashFile) lineAfter(offset int64) ([]byte, error) {
+	reader := bufio.NewReader(h.sectionFrom(offset))
+	if offset > 0 {
+		if _, err := reader.ReadSlice('\n'); err != nil {
+			return nil, nil
+		}
+	}
+	line, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", breachDBError, err)
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, nil
+	}
+	return line, nil
+}
+
+func lineHash(line []byte) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(string(line)), ":")
+	return strings.ToUpper(hash)
+}
+
+func lineCount(line []byte) string {
+	_, count, _ := strings.Cut(strings.TrimSpace(string(line)), ":")
+	return count
+}
+
+// bloomFilter answers from a few bytes read on demand, the file is never loaded in memory.
+// Its header is the magic, the number of bits, the number of hashes and the number of entries
+type bloomFilter struct {
+	file    *os.File
+	bits    uint64
+	hashes  uint32
+	entries uint64
+}
+
+func openBloomFilter(file *os.File, size int64) (*bloomFilter, error) {
+	header := make([]byte, bloomHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", breachDBError, err)
+	}
+	offset := len(bloomMagic)
+	bloom := &bloomFilter{
+		file:    file,
+		bits:    binary.BigEndian.Uint64(header[offset:]),
+		hashes:  binary.BigEndian.Uint32(header[offset+8:]),
+		entries: binary.BigEndian.Uint64(header[offset+12:]),
+	}
+	if bloom.bits == 0 || bloom.hashes == 0 || int64(bloomHeaderSize)+int64((bloom.bits+7)/8) != size {
+		return nil, fmt.Errorf("%w: truncated bloom filter", breachDBError)
+	}
+	return bloom, nil
+}
+
+func (b *bloomFilter) Close() error {
+	return b.file.Close()
+}
+
+func (b *bloomFilter) lookup(hash [sha1.Size]byte) (bool, int, error) {
+	var value [1]byte
+	for _, position := range bloomPositions(hash, b.bits, b.hashes) {
+		if _, err := b.file.ReadAt(value[:], int64(bloomHeaderSize)+int64(position/8)); err != nil {
+			return false, 0, fmt.Errorf("%w: %v", breachDBError, err)
+		}
+		if value[0]&(1<<(position%8)) == 0 {
+			return false, 0, nil
+		}
+	}
+	return true, 0, nil
+}
+
+// bloomPositions derives the bits of a hash by double hashing. SHA-1 output is already uniform,
+// so two halves of it are enough
+func bloomPositions(hash [sha1.Size]byte, bits uint64, hashes uint32) []uint64 {
+	h1 := binary.BigEndian.Uint64(hash[0:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16]) | 1
+	positions := make([]uint64, hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % bits
+	}
+	return positions
+}
+
+// bloomSize returns the bits and hashes giving the false positive rate for that many entries
+func bloomSize(entries uint64, falsePositives float64) (uint64, uint32) {
+	n := float64(max(entries, 1))
+	bits := math.Ceil(-n * math.Log(falsePositives) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(bits / n * math.Ln2)
+	return uint64(bits), uint32(max(hashes, 1))
+}
+
+// buildBloomFilter reads the hash file twice: once to size the filter and once to fill it. The bits
+// are set in a memory mapping of the new file, so the filter of the whole corpus (about 1.7 GB at the
+// default rate) never has to fit in memory. Like writeFileAtomic, the file replaces target once synced
+func buildBloomFilter(source, target string, falsePositives float64) (uint64, error) {
+	if falsePositives <= 0 || falsePositives >= 1 {
+		return 0, fmt.Errorf("%w: the false positive rate must be between 0 and 1", breachDBError)
+	}
+
+	var entries uint64
+	if err := readHashes(source, func([sha1.Size]byte) { entries++ }); err != nil {
+		return 0, err
+	}
+	if entries == 0 {
+		return 0, fmt.Errorf("%w: %s has no hashes", breachDBError, source)
+	}
+	bits, hashes := bloomSize(entries, falsePositives)
+
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("creating directory: %v", err)
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("creating temporary file: %v", err)
+	}
+	tmpPath := file.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// the file starts as zeros without writing them, then only the header is written
+	size := int64(bloomHeaderSize) + int64((bits+7)/8)
+	if err := file.Chmod(0644); err != nil {
+		return 0, fmt.Errorf("setting permissions: %v", err)
+	}
+	if err := file.Truncate(size); err != nil {
+		return 0, fmt.Errorf("writing %s: %v", target, err)
+	}
+	if _, err := file.WriteAt(bloomHeader(bits, hashes, entries), 0); err != nil {
+		return 0, fmt.Errorf("writing %s: %v", target, err)
+	}
+
+	mapped, err := unix.Mmap(int(file.Fd()), 0, int(size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return 0, fmt.Errorf("mapping %s: %v", target, err)
+	}
+	filter := mapped[bloomHeaderSize:]
+	err = readHashes(source, func(hash [sha1.Size]byte) {
+		for _, position := range bloomPositions(hash, bits, hashes) {
+			filter[position/8] |= 1 << (position % 8)
+		}
+	})
+	if unmapErr := unix.Munmap(mapped); err == nil && unmapErr != nil {
+		err = fmt.Errorf("unmapping %s: %v", target, unmapErr)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("syncing %s: %v", target, err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("closing %s: %v", target, err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return 0, fmt.Errorf("replacing %s: %v", target, err)
+	}
+	committed = true
+	if err := syncDir(dir); err != nil {
+		return 0, err
+	}
+	return entries, nil
+}
+
+func bloomHeader(bits uint64, hashes uint32, entries uint64) []byte {
+	header := append(make([]byte, 0, bloomHeaderSize), bloomMagic...)
+	header = binary.BigEndian.AppendUint64(header, bits)
+	header = binary.BigEndian.AppendUint32(header, hashes)
+	return binary.BigEndian.AppendUint64(header, entries)
+}
+
+func readHashes(path string, each func([sha1.Size]byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", breachDBError, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		decoded, err := hex.DecodeString(lineHash(line))
+		if err != nil || len(decoded) != sha1.Size {
+			return fmt.Errorf("%w: line %d of %s is not a SHA-1 hash", breachDBError, number, path)
+		}
+		each([sha1.Size]byte(decoded))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", breachDBError, err)
+	}
+	return nil
+}
+
+// breachDetail describes a breached password for warnings and audit findings
+func breachDetail(count int) string {
+	if count > 0 {
+		return fmt.Sprintf("seen %d times in data breaches", count)
+	}
+	return "found in the breached passwords list"
+}
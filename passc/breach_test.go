@@ -0,0 +1,151 @@
+package passc
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var breachedPasswords = []string{"password", "123456", "qwerty", "letmein", "P@ssw0rd"}
+
+// writeHashFile writes a Pwned Passwords style file: the breached passwords among filler hashes,
+// ordered by hash, with CRLF line endings like the downloaded file
+func writeHashFile(t *testing.T, fillers int) string {
+	t.Helper()
+	var lines []string
+	for i, password := range breachedPasswords {
+		hash := passwordHash(password)
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(hash[:])), i+1))
+	}
+	for i := range fillers {
+		hash := sha1.Sum([]byte(fmt.Sprintf("filler-%d", i)))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(hash[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHashFileLookup(t *testing.T) {
+	path := writeHashFile(t, 3000)
+	breaches, err := openBreachDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer breaches.Close()
+	if _, ok := breaches.(*hashFile); !ok {
+		t.Fatalf("expected a hash file, got %T", breaches)
+	}
+
+	for i, password := range breachedPasswords {
+		found, count, err := breaches.lookup(passwordHash(password))
+		if err != nil || !found || count != i+1 {
+			t.Errorf("%s: expected found with count %d, got %v %d %v", password, i+1, found, count, err)
+		}
+	}
+
+	// every line is found, first and last ones included
+	content, _ := os.ReadFile(path)
+	for _, line := range strings.Fields(string(content)) {
+		hash, _ := hex.DecodeString(lineHash([]byte(line)))
+		if found, _, err := breaches.lookup([sha1.Size]byte(hash)); err != nil || !found {
+			t.Fatalf("%s not found: %v", line, err)
+		}
+	}
+
+	for _, password := range []string{"Xk9$mQ2vLp3@wR7n", "correcthorsebatterystaple", ""} {
+		if found, _, err := breaches.lookup(passwordHash(password)); err != nil || found {
+			t.Errorf("%q must not be found: %v", password, err)
+		}
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	source := writeHashFile(t, 3000)
+	target := filepath.Join(t.TempDir(), "breach.bloom")
+
+	entries, err := buildBloomFilter(source, target, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries != uint64(3000+len(breachedPasswords)) {
+		t.Errorf("unexpected entries %d", entries)
+	}
+
+	breaches, err := openBreachDB(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer breaches.Close()
+	if _, ok := breaches.(*bloomFilter); !ok {
+		t.Fatalf("expected a bloom filter, got %T", breaches)
+	}
+
+	for _, password := range breachedPasswords {
+		if found, _, err := breaches.lookup(passwordHash(password)); err != nil || !found {
+			t.Errorf("%s must be found: %v", password, err)
+		}
+	}
+
+	falsePositives := 0
+	for i := range 10000 {
+		if found, _, _ := breaches.lookup(passwordHash(fmt.Sprintf("not-breached-%d", i))); found {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("expected about 10 false positives, got %d", falsePositives)
+	}
+
+	// rebuilding replaces the filter and leaves no temporary file behind
+	if _, err := buildBloomFilter(writeHashFile(t, 10), target, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(filepath.Dir(target)); len(files) != 1 {
+		t.Errorf("unexpected files %v", files)
+	}
+	rebuilt, err := openBreachDB(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rebuilt.Close()
+	if bloom := rebuilt.(*bloomFilter); bloom.entries != uint64(10+len(breachedPasswords)) {
+		t.Errorf("unexpected entries %d", bloom.entries)
+	}
+}
+
+func TestBreachDBErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := openBreachDB(filepath.Join(dir, "missing")); !errors.Is(err, breachDBError) {
+		t.Errorf("expected breach db error, got %v", err)
+	}
+
+	truncated := filepath.Join(dir, "truncated.bloom")
+	if err := os.WriteFile(truncated, []byte(bloomMagic+"short"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openBreachDB(truncated); !errors.Is(err, breachDBError) {
+		t.Errorf("expected breach db error, got %v", err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("not a hash\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buildBloomFilter(invalid, filepath.Join(dir, "out.bloom"), 0.001); !errors.Is(err, breachDBError) {
+		t.Errorf("expected breach db error, got %v", err)
+	}
+	if _, err := buildBloomFilter(writeHashFile(t, 1), filepath.Join(dir, "out.bloom"), 1.5); !errors.Is(err, breachDBError) {
+		t.Errorf("expected breach db error, got %v", err)
+	}
+}
@@ -1,14 +1,17 @@
 package passc
 
 import (
+	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
-	"github.com/javiorfo/nilo"
 	"github.com/javiorfo/steams/v2"
 	"github.com/spf13/cobra"
 )
@@ -17,33 +20,318 @@ func Builder() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use: passcAppCommandName,
 	}
+	rootCmd.PersistentFlags().StringVarP(&storeFlag, "store", "s", "", "Vault name or store file path (overrides "+passcStoreEnv+")")
 
 	rootCmd.AddCommand(add())
+	rootCmd.AddCommand(agentCmd())
+	rootCmd.AddCommand(audit())
+	rootCmd.AddCommand(backupCmd())
+	rootCmd.AddCommand(breachDBCmd())
 	rootCmd.AddCommand(copy())
 	rootCmd.AddCommand(edit())
 	rootCmd.AddCommand(export())
+	rootCmd.AddCommand(history())
 	rootCmd.AddCommand(importer())
 	rootCmd.AddCommand(list())
 	rootCmd.AddCommand(logout())
+	rootCmd.AddCommand(migrate())
+	rootCmd.AddCommand(mv())
+	rootCmd.AddCommand(otp())
+	rootCmd.AddCommand(passphrase())
 	rootCmd.AddCommand(password())
+	rootCmd.AddCommand(passwd())
 	rootCmd.AddCommand(remove())
+	rootCmd.AddCommand(status())
+	rootCmd.AddCommand(tagCmd())
+	rootCmd.AddCommand(tags())
+	rootCmd.AddCommand(trashCmd())
+	rootCmd.AddCommand(vaultCmd())
 	rootCmd.AddCommand(version())
 
 	return rootCmd
 }
 
+// printReadError logs out on a wrong master password so it can be entered again
+func printReadError(err error) {
+	if errors.Is(err, invalidPasswordError) {
+		fmt.Println(passcInvalidPassword)
+		filePath, err := storeFilePath()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := lockSession(filePath); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	fmt.Println(err)
+}
+
+// warnWeakPassword only warns: the entry is saved anyway, some sites impose weak passwords
+func warnWeakPassword(data Data, minScore int) {
+	strength := estimateStrength(data.Password, data.Name, data.Username)
+	if strength.Score >= minScore {
+		return
+	}
+	fmt.Printf(passcWeakPasswordText, data.Name, strength.Score, strength.CrackTime())
+	if strength.Warning != "" {
+		fmt.Printf(passcStrengthHintText, strength.Warning)
+	}
+}
+
+// warnBreachedPassword looks the password up in the local breach database. Without one there is nothing to check
+func warnBreachedPassword(data Data, config *Config) {
+	breaches, err := configuredBreachDB(config)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if breaches == nil {
+		return
+	}
+	defer breaches.Close()
+
+	found, count, err := breaches.lookup(passwordHash(data.Password))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if found {
+		fmt.Printf(passcBreachedPasswordText, data.Name, breachDetail(count))
+	}
+}
+
+// exit ends passc with a status code scripts can check. Replaced in tests
+var exit = os.Exit
+
+// confirm asks a yes/no question before a change that can not be undone. Replaced in tests
+var confirm = func(question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// policyFlags adjust the generator profile of the password command
+type policyFlags struct {
+	profile     string
+	symbolSet   string
+	exclude     string
+	noAmbiguous bool
+	noRepeats   bool
+	minLower    int
+	minUpper    int
+	minDigits   int
+	minSymbols  int
+}
+
+// characterFlags only make sense for passwords, not passphrases
+var characterFlags = []string{"symbols", "exclude", "no-ambiguous", "no-repeats", "min-lower", "min-upper", "min-digits", "min-symbols"}
+
+func (f *policyFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.profile, "profile", "", "Generator profile of config.json")
+	cmd.Flags().StringVar(&f.symbolSet, "symbols", "", "Symbols to use instead of "+passcCharsetSymbols)
+	cmd.Flags().StringVar(&f.exclude, "exclude", "", "Characters to leave out")
+	cmd.Flags().BoolVar(&f.noAmbiguous, "no-ambiguous", false, "Leave out characters easy to confuse ("+passcCharsetAmbiguous+")")
+	cmd.Flags().BoolVar(&f.noRepeats, "no-repeats", false, "Use each character at most once")
+	cmd.Flags().IntVar(&f.minLower, "min-lower", 0, "Minimum lowercase letters")
+	cmd.Flags().IntVar(&f.minUpper, "min-upper", 0, "Minimum uppercase letters")
+	cmd.Flags().IntVar(&f.minDigits, "min-digits", 0, "Minimum digits")
+	cmd.Flags().IntVar(&f.minSymbols, "min-symbols", 0, "Minimum symbols")
+}
+
+// generatorProfile applies the flags given on top of the profile chosen with --profile
+func (f policyFlags) generatorProfile(cmd *cobra.Command, config *Config) (Profile, error) {
+	profile, err := config.profile(f.profile)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	flags := cmd.Flags()
+	if profile.Passphrase != nil {
+		for _, flag := range characterFlags {
+			if flags.Changed(flag) {
+				return Profile{}, errors.New(passcProfileFlagsErr)
+			}
+		}
+		return profile, nil
+	}
+
+	if flags.Changed("symbols") {
+		profile.SymbolSet = f.symbolSet
+	}
+	if flags.Changed("exclude") {
+		profile.Exclude = f.exclude
+	}
+	if flags.Changed("no-ambiguous") {
+		profile.ExcludeAmbiguous = f.noAmbiguous
+	}
+	if flags.Changed("no-repeats") {
+		profile.NoRepeats = f.noRepeats
+	}
+	for flag, target := range map[string]*int{"min-lower": &profile.MinLower, "min-upper": &profile.MinUpper, "min-digits": &profile.MinDigits, "min-symbols": &profile.MinSymbols} {
+		if flags.Changed(flag) {
+			*target, _ = flags.GetInt(flag)
+		}
+	}
+	return profile, nil
+}
+
+// entryFlags are the entry properties add and edit take as flags
+type entryFlags struct {
+	password string
+	info     string
+	username string
+	urls     []string
+	notes    string
+	tags     []string
+	fields   []string
+	otp      string
+	otpType  string
+	otpAlgo  string
+	digits   int
+	period   int
+}
+
+func (f *entryFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.password, "password", "p", "", "Password for the entry")
+	cmd.Flags().StringVarP(&f.info, "info", "i", "", "Additional info for the entry")
+	cmd.Flags().StringVarP(&f.username, "username", "u", "", "Username or login of the entry")
+	cmd.Flags().StringSliceVar(&f.urls, "url", nil, "URL of the entry (repeat it or separate with commas)")
+	cmd.Flags().StringVarP(&f.notes, "notes", "n", "", "Notes for the entry")
+	cmd.Flags().StringSliceVarP(&f.tags, "tag", "t", nil, "Tag of the entry (repeat it or separate with commas)")
+	cmd.Flags().StringArrayVarP(&f.fields, "field", "f", nil, "Custom field as [type:]name=value, type is text, hidden, url or email. An empty value removes it")
+	cmd.Flags().StringVar(&f.otp, "otp", "", "One-time password as an otpauth:// URI or a base32 secret. An empty value removes it")
+	cmd.Flags().StringVar(&f.otpType, "otp-type", otpTypeTOTP, "One-time password type: totp or hotp")
+	cmd.Flags().StringVar(&f.otpAlgo, "otp-algorithm", otpDefaultAlgorithm, "One-time password algorithm: SHA1, SHA256 or SHA512")
+	cmd.Flags().IntVar(&f.digits, "otp-digits", otpDefaultDigits, "One-time password digits")
+	cmd.Flags().IntVar(&f.period, "otp-period", otpDefaultPeriod, "TOTP period in seconds")
+}
+
+// apply sets on the entry every property whose flag was given, except the password
+func (f entryFlags) apply(cmd *cobra.Command, data *Data) error {
+	flags := cmd.Flags()
+	if f.info != "" {
+		data.Info = f.info
+	}
+	if flags.Changed("username") {
+		data.Username = f.username
+	}
+	if flags.Changed("url") {
+		for _, u := range f.urls {
+			if err := validateURL(u); err != nil {
+				return err
+			}
+		}
+		data.URLs = f.urls
+	}
+	if flags.Changed("notes") {
+		data.Notes = f.notes
+	}
+	if flags.Changed("tag") {
+		for _, tag := range f.tags {
+			if err := validateTag(tag); err != nil {
+				return err
+			}
+		}
+		data.Tags = f.tags
+	}
+	for _, flag := range f.fields {
+		field, err := parseField(flag)
+		if err != nil {
+			return err
+		}
+		data.setField(field)
+	}
+	return f.applyOTP(cmd, data)
+}
+
+// applyOTP sets the one-time password. The --otp-* flags adjust a bare secret or the current one
+func (f entryFlags) applyOTP(cmd *cobra.Command, data *Data) error {
+	flags := cmd.Flags()
+	if flags.Changed("otp") {
+		if f.otp == "" {
+			data.OTP = nil
+			return nil
+		}
+		otp, err := newOTP(f.otp)
+		if err != nil {
+			return err
+		}
+		data.OTP = otp
+	}
+
+	changed := flags.Changed("otp-type") || flags.Changed("otp-algorithm") || flags.Changed("otp-digits") || flags.Changed("otp-period")
+	if !changed {
+		return nil
+	}
+	if data.OTP == nil {
+		return fmt.Errorf("%w: --otp is missing", otpError)
+	}
+
+	otp := *data.OTP
+	if flags.Changed("otp-type") {
+		otp.Type = strings.ToLower(f.otpType)
+		otp.Period = 0
+		if otp.Type == otpTypeTOTP {
+			otp.Period = otpDefaultPeriod
+		}
+	}
+	if flags.Changed("otp-algorithm") {
+		otp.Algorithm = strings.ToUpper(f.otpAlgo)
+	}
+	if flags.Changed("otp-digits") {
+		otp.Digits = f.digits
+	}
+	if flags.Changed("otp-period") {
+		otp.Period = f.period
+	}
+	if err := otp.validate(); err != nil {
+		return err
+	}
+	data.OTP = &otp
+	return nil
+}
+
 func add() *cobra.Command {
-	var password string
-	var info string
+	var flags entryFlags
+	var usePassphrase bool
+	var profileName string
 
 	add := &cobra.Command{
 		Use:     "add [name]",
 		Short:   "Add a new entry to the store",
-		Long:    "Add a new entry to the store. Password (-p flag) and Info (-i flag) are optionals",
-		Example: "passc add acme -p p4$$w0rd -i \"acme.com page\"",
+		Long:    "Add a new entry to the store. Password (-p flag), Info (-i flag) and the rest of the properties are optionals. Without a password a random one is generated, or a passphrase with --passphrase, or one following a generator profile of config.json with --profile",
+		Example: "passc add acme -p p4$$w0rd -i \"acme.com page\"\npassc add acme -u john --url https://acme.com -t work -f hidden:pin=1234\npassc add laptop --passphrase\npassc add bank --profile bank",
 		Args:    cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			encryptor, err := checkMasterPassword()
+			switch {
+			case profileName != "" && flags.password != "":
+				fmt.Println(passcProfilePasswordErr)
+				return
+			case profileName != "" && usePassphrase:
+				fmt.Println(passcProfilePassphraseErr)
+				return
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
+			profile, err := config.profile(profileName)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if usePassphrase {
+				passphrase := defaultPassphrasePolicy()
+				profile.Passphrase = &passphrase
+			}
+
+			vault, err := openVault()
 			if err != nil {
 				if errors.Is(err, masterPasswordError) {
 					fmt.Println(err)
@@ -53,60 +341,273 @@ func add() *cobra.Command {
 				return
 			}
 			name := args[0]
+			if err := validateName(name); err != nil {
+				fmt.Println(err)
+				return
+			}
 
-			data, err := newData(name, password, info)
+			unlock, err := lockVault(vault)
 			if err != nil {
-				log.Println("generating random password: ", err.Error())
+				fmt.Println(err)
 				return
 			}
+			defer unlock()
 
-			json, err := data.toJSON()
-			if err != nil {
-				log.Println("converting data to JSON: ", err.Error())
+			if _, err := vault.Get(name); err == nil {
+				fmt.Printf(passcNameTakenText, name)
+				return
+			} else if !errors.Is(err, entryNotFoundError) {
+				printReadError(err)
 				return
 			}
 
-			content, err := encryptor.readEncryptedText()
-			if err != nil {
-				if !errors.Is(err, emptyFile) {
-					fmt.Println(passcInvalidPassword)
-					if err := removeTemp(); err != nil {
-						fmt.Println(err)
-					}
+			password := flags.password
+			if password == "" {
+				if password, err = profile.generate(); err != nil {
+					log.Println("generating random password: ", err.Error())
 					return
 				}
 			}
 
-			if isNameTaken(content, name) {
-				fmt.Printf(passcNameTakenText, name)
+			data, err := newData(name, password, flags.info)
+			if err != nil {
+				log.Println("generating random password: ", err.Error())
+				return
+			}
+			if err := flags.apply(cmd, data); err != nil {
+				fmt.Println(err)
 				return
 			}
+			data.CreatedAt = time.Now()
+			data.UpdatedAt = data.CreatedAt
 
-			if err := encryptor.encryptText(*json, true); err != nil {
+			if err := vault.Put(*data); err != nil {
 				log.Println("error encrypting data: ", err.Error())
 				return
 			}
 
 			fmt.Printf(passcEntryCreatedText, name)
+			if flags.password != "" {
+				warnWeakPassword(*data, config.MinPasswordScore)
+				warnBreachedPassword(*data, config)
+			}
 			data.print(true)
-			makeBackUp()
 		},
 	}
 
-	add.Flags().StringVarP(&password, "password", "p", "", "Password for the entry")
-	add.Flags().StringVarP(&info, "info", "i", "", "Additional info for the entry")
+	flags.register(add)
+	add.Flags().BoolVar(&usePassphrase, "passphrase", false, "Generate a passphrase instead of a password when -p is not given")
+	add.Flags().StringVar(&profileName, "profile", "", "Generate the password with a generator profile of config.json")
 
 	return add
 }
 
-func copy() *cobra.Command {
+func agentCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:     "copy [name]",
-		Short:   "Copy password to clipboard",
-		Long:    "Copy password to clipboard",
-		Example: "passc copy name_here",
+		Use:     "agent",
+		Short:   "Run the session agent",
+		Long:    "Run the session agent. It keeps the unlocked store key in memory and serves it to passc over a user-only Unix socket. It is started automatically after login",
+		Example: "passc agent &",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runAgent(); err != nil {
+				if errors.Is(err, agentRunningError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("running agent: ", err.Error())
+			}
+		},
+	}
+}
+
+func audit() *cobra.Command {
+	var tags []string
+	var asJSON bool
+	var failOn, breachDBPath string
+	audit := &cobra.Command{
+		Use:     "audit",
+		Short:   "Report reused, weak and old passwords",
+		Long:    "Report breached, reused, weak and old passwords, passwords equal to the entry name and entries without username or URL. Breached passwords are looked up offline in --breach-db or the breachDB of config.json. It exits with status 1 when a finding reaches the failOn severity of config.json (high by default)",
+		Example: "passc audit\npassc audit --json --fail-on medium\npassc audit --tag client-x\npassc audit --breach-db pwned-passwords-sha1-ordered-by-hash.txt",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+			if cmd.Flags().Changed("fail-on") {
+				if err := validateFailOn(failOn); err != nil {
+					fmt.Println(err)
+					return
+				}
+				config.Audit.FailOn = failOn
+			}
+
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+
+			items, err := vault.List()
+			if err != nil {
+				printReadError(err)
+				return
+			}
+			if items = selectByTags(items, tags); len(items) == 0 && len(tags) > 0 {
+				fmt.Printf(passcNoTaggedText, strings.Join(tags, ", "))
+				return
+			}
+
+			var breaches breachDB
+			if breachDBPath != "" {
+				breaches, err = openBreachDB(breachDBPath)
+			} else {
+				breaches, err = configuredBreachDB(config)
+			}
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if breaches != nil {
+				defer breaches.Close()
+			}
+
+			report, err := auditEntries(items, config.Audit, config.MinPasswordScore, breaches, time.Now())
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if asJSON {
+				content, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					log.Println("encoding report: ", err.Error())
+					return
+				}
+				fmt.Println(string(content))
+			} else {
+				report.print()
+			}
+
+			if report.Failed {
+				exit(1)
+			}
+		},
+	}
+
+	audit.Flags().StringSliceVarP(&tags, "tag", "t", nil, "Only audit entries with this tag (repeat it to require several)")
+	audit.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	audit.Flags().StringVar(&failOn, "fail-on", "", "Lowest severity that makes the command fail: low, medium, high or none")
+	audit.Flags().StringVar(&breachDBPath, "breach-db", "", "Bloom filter or sorted SHA-1 file of breached passwords")
+
+	return audit
+}
+
+func backupCmd() *cobra.Command {
+	backup := &cobra.Command{
+		Use:     "backup",
+		Short:   "Manage store backups",
+		Long:    "Manage store backups. A new generation is taken after every change and old ones are pruned following the backup settings of config.json",
+		Example: "passc backup list",
+	}
+
+	backup.AddCommand(&cobra.Command{
+		Use:     "list",
+		Short:   "List backups",
+		Long:    "List backups, newest first",
+		Example: "passc backup list",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			filePath, err := storeFilePath()
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			backups, err := listBackups(filePath)
+			if err != nil {
+				log.Println("listing backups: ", err.Error())
+				return
+			}
+			if len(backups) == 0 {
+				fmt.Println(passcNoBackupsText)
+				return
+			}
+
+			fmt.Println(passcBackupsTitle)
+			for i, b := range backups {
+				branch := "├──"
+				if i == len(backups)-1 {
+					branch = "└──"
+				}
+				fmt.Printf("%s \033[1m%s\033[0m %s (%d bytes)\n", branch, b.ID, b.CreatedAt.Format("2006-01-02 15:04:05"), b.Size)
+			}
+		},
+	})
+
+	backup.AddCommand(&cobra.Command{
+		Use:     "create",
+		Short:   "Back up the store now",
+		Long:    "Back up the store now",
+		Example: "passc backup create",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			filePath, err := storeFilePath()
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
+			lock := newStoreLock(filePath)
+			if err := lock.Lock(); err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer lock.Unlock()
+
+			if _, err := os.Stat(filePath); os.IsNotExist(err) {
+				fmt.Println(passcEmptyFile)
+				return
+			}
+
+			created, err := makeBackUp(filePath, config.Backup)
+			if err != nil {
+				if errors.Is(err, emptyFile) {
+					fmt.Println(passcEmptyFile)
+					return
+				}
+				log.Println("backing up store: ", err.Error())
+				return
+			}
+			fmt.Printf(passcBackupCreatedText, created.ID)
+		},
+	})
+
+	backup.AddCommand(&cobra.Command{
+		Use:     "restore [id]",
+		Short:   "Replace the store with a backup",
+		Long:    "Replace the store with a backup. The backup must open with the current master password and the replaced store is kept as a new backup",
+		Example: "passc backup restore 20240101-093000.000",
 		Args:    cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
 			encryptor, err := checkMasterPassword()
 			if err != nil {
 				if errors.Is(err, masterPasswordError) {
@@ -117,176 +618,1148 @@ func copy() *cobra.Command {
 				return
 			}
 
-			content, err := encryptor.readEncryptedText()
+			lock := newStoreLock(encryptor.FilePath)
+			if err := lock.Lock(); err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer lock.Unlock()
+
+			replaced, err := restoreBackup(encryptor, args[0], config.Backup)
+			if errors.Is(err, invalidPasswordError) && encryptor.MasterPassword == "" {
+				// unlocked by the agent, but the backup was encrypted under another salt
+				fmt.Println(passcLoginTitle)
+				if encryptor.MasterPassword, err = readMasterPassword(passcMasterPasswordText); err != nil {
+					fmt.Println(err)
+					return
+				}
+				replaced, err = restoreBackup(encryptor, args[0], config.Backup)
+			}
+			if err != nil {
+				if errors.Is(err, backupNotFoundError) {
+					fmt.Println(err)
+					return
+				}
+				printReadError(err)
+				return
+			}
+
+			if encryptor.MasterPassword != "" {
+				// the restored store may use another salt than the current session
+				if err := startSession(&Encryptor{MasterPassword: encryptor.MasterPassword, FilePath: encryptor.FilePath, KDF: config.KDF}, config.Session); err != nil {
+					log.Println("starting session: ", err.Error())
+				}
+			}
+
+			replacedID := "-"
+			if replaced != nil {
+				replacedID = replaced.ID
+			}
+			fmt.Printf(passcBackupRestoredText, args[0], replacedID)
+		},
+	})
+
+	return backup
+}
+
+func breachDBCmd() *cobra.Command {
+	breachdb := &cobra.Command{
+		Use:     "breachdb",
+		Short:   "Manage the offline breached passwords database",
+		Long:    "Manage the offline breached passwords database. Download the Pwned Passwords SHA-1 file ordered by hash and use it directly or build a compact bloom filter from it",
+		Example: "passc breachdb build pwned-passwords-sha1-ordered-by-hash.txt",
+	}
+
+	var output string
+	var falsePositives float64
+	build := &cobra.Command{
+		Use:     "build [hash file]",
+		Short:   "Build a bloom filter from a SHA-1 hash file",
+		Long:    "Build a bloom filter from a SHA-1 hash file (one HASH or HASH:COUNT per line). It is written to $HOME/.passcualito/breach.bloom unless -o is given, where add, edit and audit use it. A bloom filter may report a few passwords as breached when they are not (--false-positives)",
+		Example: "passc breachdb build pwned-passwords-sha1-ordered-by-hash.txt\npassc breachdb build hashes.txt -o /data/breach.bloom --false-positives 0.0001",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			target := output
+			if target == "" {
+				defaultPath, err := defaultBreachDBPath()
+				if err != nil {
+					log.Println("finding breach database: ", err.Error())
+					return
+				}
+				target = defaultPath
+			}
+
+			entries, err := buildBloomFilter(args[0], target, falsePositives)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf(passcBreachDBBuiltText, entries, target)
+		},
+	}
+	build.Flags().StringVarP(&output, "output", "o", "", "Bloom filter path")
+	build.Flags().Float64Var(&falsePositives, "false-positives", 0.001, "Rate of passwords wrongly reported as breached")
+
+	breachdb.AddCommand(build)
+
+	return breachdb
+}
+
+func copy() *cobra.Command {
+	var fieldName string
+	copy := &cobra.Command{
+		Use:               "copy [name]",
+		Short:             "Copy password to clipboard",
+		Long:              "Copy password to clipboard, or the value of a custom field with --field",
+		Example:           "passc copy name_here\npassc copy name_here --field pin",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+			name := args[0]
+
+			data, err := vault.Get(name)
+			if err != nil {
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
+					return
+				}
+				printReadError(err)
+				return
+			}
+
+			value := data.Password
+			if fieldName != "" {
+				field, ok := data.field(fieldName)
+				if !ok {
+					fmt.Printf(passcNameNotFoundText, name+"/"+fieldName)
+					return
+				}
+				value = field.Value
+			}
+
+			if err := clipboard.WriteAll(value); err != nil {
+				log.Println("copying to clipboard: ", err.Error())
+				return
+			}
+			fmt.Printf(passcClipboardText, name)
+		},
+	}
+
+	copy.Flags().StringVarP(&fieldName, "field", "f", "", "Copy this custom field instead of the password")
+
+	return copy
+}
+
+func edit() *cobra.Command {
+	var flags entryFlags
+	var revert int
+	var regenerate bool
+	var profileName string
+	edit := &cobra.Command{
+		Use:               "edit [name]",
+		Short:             "Edit the entry.",
+		Long:              "Edit the entry. Password with -p, Info with -i and the rest of the properties with their flags. Each one is optional. The replaced password is kept in the history, --revert n restores the nth previous one (see passc history). --regenerate replaces it with a random one, following a generator profile of config.json with --profile",
+		Example:           "passc edit name_here -p 1234 -i \"some info\"\npassc edit name_here --revert 1\npassc edit bank --regenerate --profile bank",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			switch {
+			case revert != 0 && flags.password != "":
+				fmt.Println(passcRevertPasswordErr)
+				return
+			case regenerate && (flags.password != "" || revert != 0):
+				fmt.Println(passcRegenerateErr)
+				return
+			case profileName != "" && !regenerate:
+				fmt.Println(passcProfileRegenerateErr)
+				return
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
+			profile, err := config.profile(profileName)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+			name := args[0]
+
+			unlock, err := lockVault(vault)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer unlock()
+
+			data, err := vault.Get(name)
+			if err != nil {
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
+					return
+				}
+				printReadError(err)
+				return
+			}
+
+			now := time.Now()
+			if flags.password != "" {
+				data.setPassword(flags.password, config.HistoryDepth, now)
+			}
+			if revert != 0 {
+				if err := data.revertPassword(revert, config.HistoryDepth, now); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+			if regenerate {
+				password, err := profile.generate()
+				if err != nil {
+					log.Println("generating random password: ", err.Error())
+					return
+				}
+				data.setPassword(password, config.HistoryDepth, now)
+			}
+			if err := flags.apply(cmd, &data); err != nil {
+				fmt.Println(err)
+				return
+			}
+			data.UpdatedAt = now
+
+			if err := vault.Put(data); err != nil {
+				log.Println("encryting text: ", err.Error())
+				return
+			}
+			fmt.Printf(passcEntryEditedText, name)
+			if flags.password != "" || revert != 0 {
+				warnWeakPassword(data, config.MinPasswordScore)
+				warnBreachedPassword(data, config)
+			}
+			data.print(true)
+		},
+	}
+
+	flags.register(edit)
+	edit.Flags().IntVarP(&revert, "revert", "r", 0, "Restore the nth previous password")
+	edit.Flags().BoolVar(&regenerate, "regenerate", false, "Replace the password with a random one")
+	edit.Flags().StringVar(&profileName, "profile", "", "Regenerate the password with a generator profile of config.json")
+
+	return edit
+}
+
+func export() *cobra.Command {
+	var tags []string
+	export := &cobra.Command{
+		Use:     "export",
+		Short:   "Export data in a JSON file",
+		Long:    "Export data in a JSON file. With --tag only the entries having every given tag",
+		Example: "passc export\npassc export --tag client-x",
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+
+			items, err := vault.List()
+			if err != nil {
+				printReadError(err)
+				return
+			}
+			if len(items) == 0 {
+				fmt.Println(passcEmptyFile)
+				return
+			}
+			if items = selectByTags(items, tags); len(items) == 0 {
+				fmt.Printf(passcNoTaggedText, strings.Join(tags, ", "))
+				return
+			}
+
+			if err := exportToFile(items); err != nil {
+				fmt.Println(passcExportErr)
+			} else {
+				fmt.Printf(passcExportText, passcExportFilename)
+			}
+		},
+	}
+
+	export.Flags().StringSliceVarP(&tags, "tag", "t", nil, "Only export entries with this tag (repeat it to require several)")
+
+	return export
+}
+
+func history() *cobra.Command {
+	return &cobra.Command{
+		Use:               "history [name]",
+		Short:             "Show the previous passwords of the entry",
+		Long:              "Show the previous passwords of the entry, newest first. Restore one with passc edit name --revert n",
+		Example:           "passc history name_here",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+			name := args[0]
+
+			data, err := vault.Get(name)
+			if err != nil {
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
+					return
+				}
+				printReadError(err)
+				return
+			}
+
+			if len(data.History) == 0 {
+				fmt.Printf(passcNoHistoryText, name)
+				return
+			}
+
+			fmt.Printf(passcHistoryTitle, name)
+			for i, change := range data.History {
+				branch := "├──"
+				if i == len(data.History)-1 {
+					branch = "└──"
+				}
+				fmt.Printf("%s \033[1m%d\033[0m %s  %s\n", branch, i+1, change.ChangedAt.Local().Format("2006-01-02 15:04:05"), change.Password)
+			}
+		},
+	}
+}
+
+func importer() *cobra.Command {
+	return &cobra.Command{
+		Use:     "import [filepath]",
+		Short:   "Import entries from a JSON file",
+		Long:    "Import entries from a JSON file",
+		Example: "passc import /path/to/file.json",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+
+			filePath := args[0]
+			dataFromJson, err := getDataSliceFromJsonFile(filePath)
+			if err != nil {
+				fmt.Println("Error getting data from JSON file:", err.Error())
+				return
+			}
+			// the whole file is rejected, like for repeated names, with the error add gives
+			for _, d := range dataFromJson {
+				if err := validateName(d.Name); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+
+			unlock, err := lockVault(vault)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer unlock()
+
+			dataSlice, err := vault.List()
+			if err != nil {
+				printReadError(err)
+				return
+			}
+
+			// Filters matched names between file.json and the actual store
+			repeatedSlice := steams.FromSlice(dataSlice).
+				Filter(func(outer Data) bool {
+					return steams.FromSlice(dataFromJson).
+						Any(predicateMatchOuterData(outer))
+				}).
+				MapToString(func(d Data) string { return d.Name }).Collect()
+
+			if len(repeatedSlice) != 0 {
+				fmt.Printf(passcImportRepeatdText, filePath, strings.Join(repeatedSlice, ", "))
+				return
+			}
+
+			if err := vault.Put(dataFromJson...); err != nil {
+				log.Println("encrypting data: ", err.Error())
+				return
+			}
+			fmt.Printf(passcImportText, filePath, len(dataFromJson))
+		},
+	}
+}
+
+func predicateMatchOuterData(outer Data) func(Data) bool {
+	return func(inner Data) bool {
+		return outer.Name == inner.Name
+	}
+}
+
+func list() *cobra.Command {
+	var tags []string
+	list := &cobra.Command{
+		Use:               "list [name]",
+		Short:             "List all properties of the entry by name",
+		Long:              "List all properties of the entry by name. A folder (ending with /) is shown as a tree, / shows every folder. --tag keeps the entries having every given tag",
+		Example:           "passc list name_here\npassc list work/\npassc list --tag client-x",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+
+			items, err := vault.List()
+			if err != nil {
+				printReadError(err)
+				return
+			}
+			if len(items) == 0 {
+				fmt.Println(passcEmptyFile)
+				return
+			}
+			if items = selectByTags(items, tags); len(items) == 0 {
+				fmt.Printf(passcNoTaggedText, strings.Join(tags, ", "))
+				return
+			}
+
+			if len(args) == 1 && isFolder(args[0]) {
+				folder := strings.TrimPrefix(args[0], folderSeparator)
+				tree := newFolderTree(items, folder)
+				if tree.isEmpty() {
+					fmt.Printf(passcNameNotFoundText, args[0])
+					return
+				}
+				fmt.Printf(passcFolderTitle, args[0])
+				tree.print("")
+				return
+			}
+
+			entries := steams.FromSlice(items).SortBy(sortByName)
+			isFiltered := len(args) == 1 || len(tags) != 0
+			if len(args) == 1 {
+				entries = entries.Filter(func(d Data) bool { return d.isNameMatch(args[0]) })
+			}
+
+			count := entries.Count()
+			if count == 0 {
+				fmt.Printf(passcNameNotFoundText, args[0])
+				return
+			}
+
+			if isFiltered {
+				fmt.Println(passcMatchTitle)
+			} else {
+				fmt.Println(passcStoreTitle)
+			}
+
+			entries.ForEachIdx(func(i int, d Data) {
+				d.print(i == count-1)
+			})
+		},
+	}
+
+	list.Flags().StringSliceVarP(&tags, "tag", "t", nil, "Only list entries with this tag (repeat it to require several)")
+
+	return list
+}
+
+// completeEntryNames suggests entries and folders when the session is unlocked. It never asks for the master password
+func completeEntryNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	vault, err := openUnlockedVault()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	items, err := vault.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	suggestions := completeName(items, toComplete)
+	for _, suggestion := range suggestions {
+		if isFolder(suggestion) {
+			// let the user keep typing inside the folder
+			return suggestions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func sortByName(d1, d2 Data) int {
+	if d1.Name < d2.Name {
+		return -1
+	}
+	return 0
+}
+
+func logout() *cobra.Command {
+	var all bool
+	logout := &cobra.Command{
+		Use:     "logout",
+		Short:   "Logout of the app",
+		Long:    "Logout of the app. This allows you to enter the master password again. Only the current store is locked unless --all is passed",
+		Example: "passc logout",
+		Run: func(cmd *cobra.Command, args []string) {
+			if all {
+				if err := lockAllSessions(); err != nil {
+					fmt.Println(err)
+				}
+				fmt.Println(passcLogoutText)
+				return
+			}
+
+			filePath, err := storeFilePath()
+			if err != nil {
+				log.Println("finding store: ", err.Error())
+				return
+			}
+			if err := lockSession(filePath); err != nil {
+				fmt.Println(err)
+			}
+			fmt.Println(passcLogoutText)
+		},
+	}
+	logout.Flags().BoolVarP(&all, "all", "a", false, "Lock every store of the current user")
+
+	return logout
+}
+
+func migrate() *cobra.Command {
+	return &cobra.Command{
+		Use:     "migrate",
+		Short:   "Upgrade a store created by an older version",
+		Long:    "Upgrade a store created by an older version to the current format. The original file is kept as a timestamped backup",
+		Example: "passc migrate",
+		Run: func(cmd *cobra.Command, args []string) {
+			filePath, err := storeFilePath()
+			if err != nil {
+				log.Println("finding store: ", err.Error())
+				return
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
+			data, err := os.ReadFile(filePath)
+			if err != nil || len(data) == 0 {
+				fmt.Println(passcEmptyFile)
+				return
+			}
+			if !isLegacyStore(data) {
+				fmt.Println(passcMigrateUpToDateText)
+				return
+			}
+
+			fmt.Println(passcLoginTitle)
+			masterPassword, err := readMasterPassword(passcMasterPasswordText)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			count, backupPath, err := migrateStore(filePath, masterPassword, config.KDF, config.Backup)
+			if err != nil {
+				if errors.Is(err, invalidPasswordError) || errors.Is(err, alreadyMigratedError) || errors.Is(err, vaultBusyError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("migrating store: ", err.Error())
+				return
+			}
+
+			if err := lockSession(filePath); err != nil {
+				fmt.Println(err)
+			}
+			if err := startSession(&Encryptor{MasterPassword: masterPassword, FilePath: filePath, KDF: config.KDF}, config.Session); err != nil {
+				log.Println("starting session: ", err.Error())
+			}
+
+			fmt.Printf(passcMigrateText, count, backupPath)
+		},
+	}
+}
+
+func mv() *cobra.Command {
+	return &cobra.Command{
+		Use:               "mv [name|folder/] [name|folder/]",
+		Short:             "Move or rename entries and folders",
+		Long:              "Move or rename an entry, or a whole folder when the first name ends with /. Moving an entry to a name ending with / keeps its name inside that folder",
+		Example:           "passc mv aws work/aws/prod\npassc mv work/aws/ work/amazon/\npassc mv github personal/",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+			src, dst := args[0], args[1]
+			if isFolder(src) && !isFolder(dst) {
+				dst += folderSeparator
+			}
+
+			unlock, err := lockVault(vault)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer unlock()
+
+			items, err := vault.List()
+			if err != nil {
+				printReadError(err)
+				return
+			}
+
+			// every target is checked first to tell which one conflicts
+			moves := make(map[string]string)
+			for _, d := range items {
+				if d.Name == src || (isFolder(src) && inFolder(d.Name, src)) {
+					moves[d.Name] = moveTarget(d.Name, src, dst)
+				}
+			}
+			count := len(moves)
+			if count == 0 {
+				fmt.Printf(passcNameNotFoundText, src)
+				return
+			}
+			for _, d := range items {
+				if _, moving := moves[d.Name]; moving {
+					continue
+				}
+				for _, target := range moves {
+					if d.Name == target {
+						fmt.Printf(passcNameTakenText, target)
+						return
+					}
+				}
+			}
+			for _, target := range moves {
+				if err := validateName(target); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+
+			// a single change, so a folder move is one write and one backup generation
+			if err := vault.Move(moves); err != nil {
+				log.Println("moving entries: ", err.Error())
+				return
+			}
+			fmt.Printf(passcEntryMovedText, src, dst, count)
+		},
+	}
+}
+
+func otp() *cobra.Command {
+	var copyCode bool
+	otp := &cobra.Command{
+		Use:               "otp [name]",
+		Short:             "Show the one-time password of the entry",
+		Long:              "Show the current one-time password of the entry and how long it lasts. HOTP counters move forward on each use",
+		Example:           "passc otp github\npassc otp github --copy",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+			name := args[0]
+
+			unlock, err := lockVault(vault)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer unlock()
+
+			data, err := vault.Get(name)
+			if err != nil {
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
+					return
+				}
+				printReadError(err)
+				return
+			}
+			if data.OTP == nil {
+				fmt.Printf(passcNoOTPErr, name, name)
+				return
+			}
+
+			now := time.Now()
+			code, err := data.OTP.code(now)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			if data.OTP.Type == otpTypeHOTP {
+				// the counter is saved before showing the code so it is never used twice
+				counter := data.OTP.Counter
+				next := *data.OTP
+				next.Counter++
+				data.OTP = &next
+				if err := vault.Put(data); err != nil {
+					log.Println("saving counter: ", err.Error())
+					return
+				}
+				if copyCode {
+					if err := clipboard.WriteAll(code); err != nil {
+						log.Println("copying to clipboard: ", err.Error())
+						return
+					}
+					fmt.Printf(passcClipboardText, name)
+					return
+				}
+				fmt.Printf(passcHOTPText, code, counter)
+				return
+			}
+
+			left := int(data.OTP.remaining(now).Seconds())
+			if copyCode {
+				if err := clipboard.WriteAll(code); err != nil {
+					log.Println("copying to clipboard: ", err.Error())
+					return
+				}
+				fmt.Printf(passcOTPCopiedText, name, left)
+				return
+			}
+			fmt.Printf(passcTOTPText, code, left)
+		},
+	}
+
+	otp.Flags().BoolVarP(&copyCode, "copy", "c", false, "Copy the code to the clipboard instead of printing it")
+
+	return otp
+}
+
+func passphrase() *cobra.Command {
+	policy := defaultPassphrasePolicy()
+	passphrase := &cobra.Command{
+		Use:     "passphrase [words]",
+		Short:   "Generates a passphrase of the number of words passed",
+		Long:    "Generates a diceware passphrase from the EFF large wordlist, 6 words by default, and shows its entropy bits. Easier to type than a password of the same strength, like a master password",
+		Example: "passc passphrase\npassc passphrase 8 --separator ' ' --capitalize --digit --symbol",
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				words, err := strconv.Atoi(args[0])
+				if err != nil {
+					fmt.Println(passcPasswordParamErrNumber)
+					return
+				}
+				if words < 1 {
+					fmt.Println(passcPasswordParamErrPositive)
+					return
+				}
+				policy.Words = words
+			}
+
+			phrase, err := generatePassphrase(policy)
+			if err != nil {
+				log.Println("generating passphrase: ", err.Error())
+				return
+			}
+			fmt.Println(phrase)
+			// stdout keeps only the passphrase so it can be piped
+			fmt.Fprintf(os.Stderr, passcEntropyText, policy.Entropy())
+		},
+	}
+
+	passphrase.Flags().StringVar(&policy.Separator, "separator", policy.Separator, "Separator between words")
+	passphrase.Flags().BoolVar(&policy.Capitalize, "capitalize", false, "Capitalize every word")
+	passphrase.Flags().BoolVar(&policy.Digit, "digit", false, "Add a digit to a random word")
+	passphrase.Flags().BoolVar(&policy.Symbol, "symbol", false, "Add a symbol to a random word")
+
+	return passphrase
+}
+
+func password() *cobra.Command {
+	var flags policyFlags
+	password := &cobra.Command{
+		Use:     "password [number]",
+		Short:   "Generates a password of the number passed",
+		Long:    "Generates a password of the number passed, 20 by default, and shows its entropy bits. --profile uses a generator profile of config.json, where the number is the length or the words of a passphrase. The other flags require characters of each kind, change the symbols or leave out characters",
+		Example: "passc password 12\npassc password --profile bank\npassc password 16 --min-digits 2 --min-symbols 2 --symbols '-_.' --no-ambiguous",
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
+			profile, err := flags.generatorProfile(cmd, config)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if len(args) == 1 {
+				number, err := strconv.Atoi(args[0])
+				if err != nil {
+					fmt.Println(passcPasswordParamErrNumber)
+					return
+				}
+				if number < 1 {
+					fmt.Println(passcPasswordParamErrPositive)
+					return
+				}
+				profile = profile.withLength(number)
+			}
+
+			psswd, err := profile.generate()
+			if err != nil {
+				if errors.Is(err, policyError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("generating random password: ", err.Error())
+				return
+			}
+			fmt.Println(psswd)
+			// stdout keeps only the password so it can be piped
+			fmt.Fprintf(os.Stderr, passcEntropyText, profile.Entropy())
+		},
+	}
+	flags.register(password)
+
+	return password
+}
+
+func passwd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "passwd",
+		Short:   "Change the master password",
+		Long:    "Change the master password. The store and all its backups are re-encrypted and the current session is closed",
+		Example: "passc passwd",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			filePath, err := storeFilePath()
+			if err != nil {
+				log.Println("finding store: ", err.Error())
+				return
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				log.Println("loading config: ", err.Error())
+				return
+			}
+
+			if stat, err := os.Stat(filePath); err != nil || stat.Size() == 0 {
+				fmt.Println(passcEmptyFile)
+				return
+			}
+
+			fmt.Println(passcChangePasswordTitle)
+			oldPassword, err := readMasterPassword(passcOldMasterPasswordText)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			current := Encryptor{MasterPassword: oldPassword, FilePath: filePath}
+			if _, err := current.readEncryptedText(); err != nil {
+				printReadError(err)
+				return
+			}
+
+			newPassword, err := readMasterPassword(passcNewMasterPasswordText)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			confirmation, err := readMasterPassword(passcConfirmPasswordText)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			if err := checkNewMasterPassword(oldPassword, newPassword, confirmation); err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			if err := rekeyStore(filePath, oldPassword, newPassword, config.KDF); err != nil {
+				if errors.Is(err, vaultBusyError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("changing master password: ", err.Error())
+				return
+			}
+
+			if err := lockSession(filePath); err != nil {
+				fmt.Println(err)
+			}
+			fmt.Println(passcMasterPasswordChanged)
+		},
+	}
+}
+
+func remove() *cobra.Command {
+	var permanent bool
+	remove := &cobra.Command{
+		Use:               "remove [name]",
+		Short:             "Remove the entry",
+		Long:              "Remove the entry if exists. It goes to the trash (see passc trash) unless --permanent is set",
+		Example:           "passc remove entry_name",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeEntryNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
+			}
+			name := args[0]
+
+			unlock, err := lockVault(vault)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer unlock()
+
+			if permanent {
+				if _, err = vault.Get(name); err == nil {
+					if !confirm(fmt.Sprintf(passcConfirmRemoveText, name)) {
+						fmt.Println(passcCancelledText)
+						return
+					}
+					err = vault.Delete(name)
+				}
+			} else {
+				err = vault.Trash(name)
+			}
+
+			if err != nil {
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
+					return
+				}
+				printReadError(err)
+				return
+			}
+
+			if permanent {
+				fmt.Printf(passcEntryRemovedText, name)
+			} else {
+				fmt.Printf(passcEntryTrashedText, name, name)
+			}
+		},
+	}
+
+	remove.Flags().BoolVar(&permanent, "permanent", false, "Delete the entry for good instead of moving it to the trash")
+
+	return remove
+}
+
+func status() *cobra.Command {
+	return &cobra.Command{
+		Use:     "status",
+		Short:   "Show whether the vault is unlocked",
+		Long:    "Show whether the vault is unlocked and when the session will lock",
+		Example: "passc status",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			filePath, err := storeFilePath()
+			if err != nil {
+				log.Println("finding store: ", err.Error())
+				return
+			}
+
+			status, err := getAgentStatus(filePath)
 			if err != nil {
-				if errors.Is(err, emptyFile) {
-					fmt.Println(err.Error())
-					return
-				}
-				fmt.Println(passcInvalidPassword)
-				if err := removeTemp(); err != nil {
-					fmt.Println(err)
-				}
+				log.Println("checking status: ", err.Error())
 				return
 			}
 
-			items := stringToDataSlice(content)
-			name := args[0]
+			if status == nil || !status.Unlocked {
+				fmt.Println(passcLockedText)
+				return
+			}
 
-			steams.FromSlice(items).Find(func(d Data) bool {
-				return d.Name == name
-			}).Map(func(d Data) Data {
-				err = clipboard.WriteAll(d.Password)
-				if err != nil {
-					log.Println("copying to clipboard: ", err.Error())
-				} else {
-					fmt.Printf(passcClipboardText, name)
-				}
-				return d
-			}).IfNil(func() {
-				fmt.Printf(passcNameNotFoundText, name)
-			})
+			fmt.Println(passcUnlockedText)
+			fmt.Println("│")
+			fmt.Println("├── \033[1midle lock:\033[0m", formatLockTime(status.IdleLocksAt))
+			fmt.Println("└── \033[1mmax lock:\033[0m ", formatLockTime(status.MaxLocksAt))
 		},
 	}
 }
 
-func edit() *cobra.Command {
-	var password string
-	var info string
-	edit := &cobra.Command{
-		Use:     "edit [name]",
-		Short:   "Edit the entry.",
-		Long:    "Edit the entry. Password with -p and Info with -i. Each one is optional",
-		Example: "passc edit name_here -p 1234 -i \"some info\"",
-		Args:    cobra.ExactArgs(1),
+func formatLockTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (in %s)", t.Format(time.DateTime), time.Until(t).Round(time.Second))
+}
+
+func vaultCmd() *cobra.Command {
+	vault := &cobra.Command{
+		Use:     "vault",
+		Short:   "Manage named vaults",
+		Long:    "Manage named vaults. Each vault has its own store, backup, session and master password",
+		Example: "passc vault create work",
+	}
+
+	vault.AddCommand(&cobra.Command{
+		Use:     "list",
+		Short:   "List vaults",
+		Long:    "List vaults. The one in use is marked with *",
+		Example: "passc vault list",
+		Args:    cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			encryptor, err := checkMasterPassword()
+			vaults, err := listVaults()
 			if err != nil {
-				if errors.Is(err, masterPasswordError) {
-					fmt.Println(err)
-					return
-				}
-				log.Println("checking Master Password: ", err.Error())
+				log.Println("listing vaults: ", err.Error())
 				return
 			}
 
-			content, err := encryptor.readEncryptedText()
+			current, err := storeFilePath()
 			if err != nil {
-				if errors.Is(err, emptyFile) {
-					fmt.Println(err.Error())
-					return
-				}
-				fmt.Println(passcInvalidPassword)
-				if err := removeTemp(); err != nil {
-					fmt.Println(err)
-				}
-				return
+				fmt.Println(err)
 			}
 
-			contentLength := len(content)
-			name := args[0]
-
-			// result of all data without the input name
-			var data Data
-			result := steams.FromSlice(strings.Split(content, passcItemSeparator)).
-				Filter(predicateByNameAndSetData(name, &data)).Fold("", reducer)
-
-			resultLength := len(result)
-			// If lengths are equal, no entry has been cut off
-			if contentLength == resultLength {
-				fmt.Printf(passcNameNotFoundText, name)
-			} else {
-				if password != "" {
-					data.Password = password
+			fmt.Println(passcVaultsTitle)
+			for i, name := range vaults {
+				path, _ := resolveStore(name)
+				branch := "├──"
+				if i == len(vaults)-1 {
+					branch = "└──"
 				}
-				if info != "" {
-					data.Info = info
-				}
-				json, err := data.toJSON()
-				if err != nil {
-					log.Println("converting data to JSON: ", err.Error())
-					return
-				}
-
-				// Add edited item to final string
-				result = result + passcItemSeparator + *json
-
-				// override store.passc completly
-				if err := encryptor.encryptText(result, false); err != nil {
-					log.Println("encryting text: ", err.Error())
-					return
+				if path == current {
+					fmt.Printf("%s \033[1m%s *\033[0m\n", branch, name)
+				} else {
+					fmt.Printf("%s %s\n", branch, name)
 				}
-				fmt.Printf(passcEntryEditedText, name)
-				data.print(true)
-				makeBackUp()
 			}
 		},
-	}
-
-	edit.Flags().StringVarP(&password, "password", "p", "", "Password for the entry")
-	edit.Flags().StringVarP(&info, "info", "i", "", "Additional info for the entry")
-
-	return edit
-}
-
-func predicateByNameAndSetData(name string, data *Data) func(string) bool {
-	return func(value string) bool {
-		isInput := strings.Contains(value, fmt.Sprintf(`"name":"%s"`, name))
-		if isInput {
-			data.fromJSON([]byte(value))
-		}
-		return !isInput
-	}
-}
+	})
 
-func export() *cobra.Command {
-	return &cobra.Command{
-		Use:     "export",
-		Short:   "Export data in a JSON file",
-		Long:    "Export data in a JSON file",
-		Example: "passc export",
+	vault.AddCommand(&cobra.Command{
+		Use:     "create [name]",
+		Short:   "Create a new vault",
+		Long:    "Create a new vault. Its master password is asked the first time it is used",
+		Example: "passc vault create work",
+		Args:    cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			encryptor, err := checkMasterPassword()
-			if err != nil {
-				if errors.Is(err, masterPasswordError) {
+			if _, err := createVault(args[0]); err != nil {
+				if errors.Is(err, vaultExistsError) || errors.Is(err, vaultNameError) {
 					fmt.Println(err)
 					return
 				}
-				log.Println("checking Master Password: ", err.Error())
+				log.Println("creating vault: ", err.Error())
 				return
 			}
+			fmt.Printf(passcVaultCreatedText, args[0], args[0])
+		},
+	})
 
-			content, err := encryptor.readEncryptedText()
-			if err != nil {
-				if errors.Is(err, emptyFile) {
-					fmt.Println(err.Error())
-					return
-				}
-				fmt.Println(passcInvalidPassword)
-				if err := removeTemp(); err != nil {
+	vault.AddCommand(&cobra.Command{
+		Use:     "use [name]",
+		Short:   "Select the vault used by default",
+		Long:    "Select the vault used by default. --store and " + passcStoreEnv + " still take precedence",
+		Example: "passc vault use work",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := useVault(args[0]); err != nil {
+				if errors.Is(err, vaultNotFoundError) || errors.Is(err, vaultNameError) {
 					fmt.Println(err)
+					return
 				}
+				log.Println("selecting vault: ", err.Error())
 				return
 			}
-
-			if err := exportToFile(content); err != nil {
-				fmt.Println(passcExportErr)
-			} else {
-				fmt.Printf(passcExportText, passcExportFilename)
-			}
+			fmt.Printf(passcVaultUsedText, args[0])
 		},
-	}
+	})
+
+	return vault
 }
 
-func importer() *cobra.Command {
-	return &cobra.Command{
-		Use:     "import [filepath]",
-		Short:   "Import entries from a JSON file",
-		Long:    "Import entries from a JSON file",
-		Example: "passc import /path/to/file.json",
-		Args:    cobra.MaximumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			encryptor, err := checkMasterPassword()
+func tagCmd() *cobra.Command {
+	tag := &cobra.Command{
+		Use:     "tag",
+		Short:   "Add or remove tags of an entry",
+		Long:    "Add or remove tags of an entry. Tags group entries across folders (see passc tags and --tag)",
+		Example: "passc tag add github shared rotate-q3",
+	}
+
+	change := func(add bool) func(cmd *cobra.Command, args []string) {
+		return func(cmd *cobra.Command, args []string) {
+			name, tags := args[0], args[1:]
+			for _, t := range tags {
+				if err := validateTag(t); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+
+			vault, err := openVault()
 			if err != nil {
 				if errors.Is(err, masterPasswordError) {
 					fmt.Println(err)
@@ -296,89 +1769,77 @@ func importer() *cobra.Command {
 				return
 			}
 
-			filePath := args[0]
-			dataFromJson, err := getDataSliceFromJsonFile(filePath)
+			unlock, err := lockVault(vault)
 			if err != nil {
-				fmt.Println("Error getting data from JSON file:", err.Error())
+				fmt.Println(err)
 				return
 			}
+			defer unlock()
 
-			content, err := encryptor.readEncryptedText()
+			data, err := vault.Get(name)
 			if err != nil {
-				if errors.Is(err, emptyFile) {
-					if err := encryptDataSlice(encryptor, dataFromJson); err != nil {
-						log.Println("encrypting data: ", err.Error())
-					}
-					fmt.Printf(passcImportText, filePath, len(dataFromJson))
-					return
-				} else {
-					fmt.Println(passcInvalidPassword)
-					if err := removeTemp(); err != nil {
-						fmt.Println(err)
-					}
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
 					return
 				}
-			}
-
-			dataSlice := stringToDataSlice(content)
-			// Filters matched names between file.json and the actual store
-			repeatedSlice := steams.FromSlice(dataSlice).
-				Filter(func(outer Data) bool {
-					return steams.FromSlice(dataFromJson).
-						Any(predicateMatchOuterData(outer))
-				}).
-				MapToString(func(d Data) string { return d.Name }).Collect()
-
-			if len(repeatedSlice) != 0 {
-				fmt.Printf(passcImportRepeatdText, filePath, strings.Join(repeatedSlice, ", "))
+				printReadError(err)
 				return
 			}
 
-			if err := encryptor.deleteContent(); err != nil {
-				log.Println("deleting file: ", err.Error())
-				return
+			var changed int
+			if add {
+				changed = data.addTags(tags...)
+			} else {
+				changed = data.removeTags(tags...)
 			}
-
-			if err := encryptDataSlice(encryptor, dataSlice); err != nil {
-				log.Println("encrypting data: ", err.Error())
+			if changed != 0 {
+				data.UpdatedAt = time.Now()
+				if err := vault.Put(data); err != nil {
+					log.Println("encrypting data: ", err.Error())
+					return
+				}
 			}
-			if err := encryptDataSlice(encryptor, dataFromJson); err != nil {
-				log.Println("encrypting data: ", err.Error())
+
+			if add {
+				fmt.Printf(passcTagsAddedText, name, strings.Join(tags, ", "))
+			} else {
+				fmt.Printf(passcTagsRemovedText, strings.Join(tags, ", "), name)
 			}
-			fmt.Printf(passcImportText, filePath, len(dataFromJson))
-			makeBackUp()
-		},
+		}
 	}
-}
 
-func predicateMatchOuterData(outer Data) func(Data) bool {
-	return func(inner Data) bool {
-		return outer.Name == inner.Name
-	}
-}
+	tag.AddCommand(&cobra.Command{
+		Use:               "add [name] [tag...]",
+		Short:             "Add tags to the entry",
+		Long:              "Add tags to the entry",
+		Example:           "passc tag add github shared rotate-q3",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeEntryNames,
+		Run:               change(true),
+	})
 
-func encryptDataSlice(encryptor *Encryptor, dataSlice []Data) error {
-	for _, v := range dataSlice {
-		json, err := v.toJSON()
-		if err != nil {
-			return fmt.Errorf("converting data to JSON: %v", err.Error())
-		}
-		if err := encryptor.encryptText(*json, true); err != nil {
-			return fmt.Errorf("encrypting data: %v", err.Error())
-		}
-	}
-	return nil
+	tag.AddCommand(&cobra.Command{
+		Use:               "rm [name] [tag...]",
+		Short:             "Remove tags from the entry",
+		Long:              "Remove tags from the entry",
+		Example:           "passc tag rm github rotate-q3",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeEntryNames,
+		Run:               change(false),
+	})
+
+	return tag
 }
 
-func list() *cobra.Command {
+func tags() *cobra.Command {
 	return &cobra.Command{
-		Use:     "list [name]",
-		Short:   "List all properties of the entry by name",
-		Long:    "List all properties of the entry by name",
-		Example: "passc list name_here",
-		Args:    cobra.MaximumNArgs(1),
+		Use:     "tags",
+		Short:   "Show every tag and how many entries have it",
+		Long:    "Show every tag and how many entries have it",
+		Example: "passc tags",
+		Args:    cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			encryptor, err := checkMasterPassword()
+			vault, err := openVault()
 			if err != nil {
 				if errors.Is(err, masterPasswordError) {
 					fmt.Println(err)
@@ -388,127 +1849,84 @@ func list() *cobra.Command {
 				return
 			}
 
-			content, err := encryptor.readEncryptedText()
+			items, err := vault.List()
 			if err != nil {
-				if errors.Is(err, emptyFile) {
-					fmt.Println(err.Error())
-					return
-				}
-				fmt.Println(passcInvalidPassword)
-				if err := removeTemp(); err != nil {
-					fmt.Println(err)
-				}
-				return
-			}
-
-			items := stringToDataSlice(content)
-			if len(items) == 0 {
-				fmt.Println(passcEmptyFile)
+				printReadError(err)
 				return
 			}
 
-			entries := steams.FromSlice(items).SortBy(sortByName)
-			isFiltered := len(args) == 1
-			if isFiltered {
-				entries = entries.Filter(func(d Data) bool { return d.isNameMatch(args[0]) })
-			}
-
-			count := entries.Count()
-			if count == 0 {
-				fmt.Printf(passcNameNotFoundText, args[0])
+			counts := countTags(items)
+			if len(counts) == 0 {
+				fmt.Println(passcNoTagsText)
 				return
 			}
 
-			if isFiltered {
-				fmt.Println(passcMatchTitle)
-			} else {
-				fmt.Println(passcStoreTitle)
+			fmt.Println(passcTagsTitle)
+			for i, c := range counts {
+				branch := "├──"
+				if i == len(counts)-1 {
+					branch = "└──"
+				}
+				fmt.Printf("%s \033[1m%s\033[0m %d\n", branch, c.Tag, c.Count)
 			}
-
-			entries.ForEachIdx(func(i int, d Data) {
-				d.print(i == count-1)
-			})
 		},
 	}
 }
 
-func sortByName(d1, d2 Data) int {
-	if d1.Name < d2.Name {
-		return -1
+func trashCmd() *cobra.Command {
+	trash := &cobra.Command{
+		Use:     "trash",
+		Short:   "Manage removed entries",
+		Long:    "Manage removed entries. passc remove moves entries to an encrypted trash inside the vault",
+		Example: "passc trash list",
 	}
-	return 0
-}
 
-func logout() *cobra.Command {
-	return &cobra.Command{
-		Use:     "logout",
-		Short:   "Logout of the app",
-		Long:    "Logout of the app. This allows you to enter the master password again",
-		Example: "passc logout",
+	trash.AddCommand(&cobra.Command{
+		Use:     "list",
+		Short:   "List trashed entries",
+		Long:    "List trashed entries and when they were removed",
+		Example: "passc trash list",
+		Args:    cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := removeTemp(); err != nil {
-				fmt.Println(err)
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
+					fmt.Println(err)
+					return
+				}
+				log.Println("checking Master Password: ", err.Error())
+				return
 			}
-			fmt.Println(passcLogoutText)
-		},
-	}
-}
 
-func password() *cobra.Command {
-	var charset string
-	password := &cobra.Command{
-		Use:     "password [number]",
-		Short:   "Generates a password of the number passed",
-		Long:    "Generates a password of the number passed. Charset (-c flag) could be a, n, an or anc",
-		Example: "passc password 12 -c an",
-		Args:    cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			number, err := strconv.Atoi(args[0])
+			trashed, err := vault.Trashed()
 			if err != nil {
-				fmt.Println(passcPasswordParamErrNumber)
+				printReadError(err)
 				return
 			}
-			if number < 1 {
-				fmt.Println(passcPasswordParamErrPositive)
+			if len(trashed) == 0 {
+				fmt.Println(passcTrashEmptyText)
 				return
 			}
 
-			var optCharset nilo.Option[string]
-			switch charset {
-			case "n":
-				optCharset = nilo.Value(passcCharsetNumeric)
-			case "a":
-				optCharset = nilo.Value(passcCharsetAlpha)
-			case "an":
-				optCharset = nilo.Value(passcCharsetAlphaNumeric)
-			case "anc":
-				optCharset = nilo.Value(passcCharsetAlphaNumericCap)
-			default:
-				optCharset = nilo.Value(passcCharset)
-			}
-
-			psswd, err := generateRandomPassword(nilo.Value(number), optCharset)
-			if err != nil {
-				log.Println("generating random password: ", err.Error())
-				return
+			fmt.Println(passcTrashTitle)
+			for i, t := range trashed {
+				branch := "├──"
+				if i == len(trashed)-1 {
+					branch = "└──"
+				}
+				fmt.Printf("%s \033[1m%s\033[0m removed %s\n", branch, t.Name, t.DeletedAt.Local().Format("2006-01-02 15:04:05"))
 			}
-			fmt.Println(*psswd)
 		},
-	}
-	password.Flags().StringVarP(&charset, "charset", "c", "", "Charset for the password (a, n, an or anc)")
-
-	return password
-}
+	})
 
-func remove() *cobra.Command {
-	return &cobra.Command{
-		Use:     "remove [name]",
-		Short:   "Remove the entry",
-		Long:    "Remove the entry if exists",
-		Example: "passc remove entry_name",
+	trash.AddCommand(&cobra.Command{
+		Use:     "restore [name]",
+		Short:   "Restore a trashed entry",
+		Long:    "Restore a trashed entry. If it was removed more than once, the latest one comes back",
+		Example: "passc trash restore entry_name",
 		Args:    cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			encryptor, err := checkMasterPassword()
+			vault, err := openVault()
 			if err != nil {
 				if errors.Is(err, masterPasswordError) {
 					fmt.Println(err)
@@ -517,62 +1935,83 @@ func remove() *cobra.Command {
 				log.Println("checking Master Password: ", err.Error())
 				return
 			}
+			name := args[0]
 
-			content, err := encryptor.readEncryptedText()
-			if err != nil {
-				if errors.Is(err, emptyFile) {
-					fmt.Println(err.Error())
+			if err := vault.Restore(name); err != nil {
+				if errors.Is(err, entryNotFoundError) {
+					fmt.Printf(passcNameNotFoundText, name)
 					return
 				}
-				fmt.Println(passcInvalidPassword)
-				if err := removeTemp(); err != nil {
+				if errors.Is(err, entryExistsError) {
+					fmt.Printf(passcNameTakenText, name)
+					return
+				}
+				printReadError(err)
+				return
+			}
+			fmt.Printf(passcEntryRestoredText, name)
+		},
+	})
+
+	var olderThan time.Duration
+	purge := &cobra.Command{
+		Use:     "purge",
+		Short:   "Delete trashed entries for good",
+		Long:    "Delete trashed entries for good. With --older-than only the ones removed before that long ago",
+		Example: "passc trash purge --older-than 720h",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			vault, err := openVault()
+			if err != nil {
+				if errors.Is(err, masterPasswordError) {
 					fmt.Println(err)
+					return
 				}
+				log.Println("checking Master Password: ", err.Error())
 				return
 			}
 
-			contentLength := len(content)
-			name := args[0]
+			unlock, err := lockVault(vault)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer unlock()
 
-			// result of all data without the input name
-			result := steams.FromSlice(strings.Split(content, passcItemSeparator)).
-				Filter(predicateByName(name)).Fold("", reducer)
+			trashed, err := vault.Trashed()
+			if err != nil {
+				printReadError(err)
+				return
+			}
 
-			resultLength := len(result)
-			// If lengths are equal, no entry has been cut off
-			if contentLength == resultLength {
-				fmt.Printf(passcNameNotFoundText, name)
-			} else {
-				if resultLength == 0 {
-					if err := encryptor.deleteContent(); err != nil {
-						log.Println("deleting file: ", err.Error())
-						return
-					}
-				} else {
-					if err := encryptor.encryptText(result, false); err != nil {
-						log.Println("encryting text: ", err.Error())
-						return
-					}
+			before := time.Now().Add(-olderThan)
+			count := 0
+			for _, t := range trashed {
+				if t.DeletedAt.Before(before) {
+					count++
 				}
-				fmt.Printf(passcEntryRemovedText, name)
-				makeBackUp()
 			}
-		},
-	}
-}
+			if count == 0 {
+				fmt.Println(passcTrashEmptyText)
+				return
+			}
+			if !confirm(fmt.Sprintf(passcConfirmPurgeText, count)) {
+				fmt.Println(passcCancelledText)
+				return
+			}
 
-func reducer(a, b string) string {
-	if a == "" {
-		return b
-	} else {
-		return a + passcItemSeparator + b
+			purged, err := vault.Purge(before)
+			if err != nil {
+				log.Println("purging trash: ", err.Error())
+				return
+			}
+			fmt.Printf(passcTrashPurgedText, purged)
+		},
 	}
-}
+	purge.Flags().DurationVar(&olderThan, "older-than", 0, "Only purge entries removed at least this long ago (e.g. 720h)")
+	trash.AddCommand(purge)
 
-func predicateByName(name string) func(string) bool {
-	return func(value string) bool {
-		return !strings.Contains(value, fmt.Sprintf(`"name":"%s"`, name))
-	}
+	return trash
 }
 
 func version() *cobra.Command {
@@ -0,0 +1,675 @@
+package passc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// runCommand executes passc against the given vault and returns what it printed
+func runCommand(t *testing.T, vault Vault, args ...string) string {
+	t.Helper()
+	// commands read config.json: keep them away from the real one unless the test set up its own HOME
+	if !strings.HasPrefix(os.Getenv("HOME"), os.TempDir()) {
+		t.Setenv("HOME", t.TempDir())
+	}
+	openVault = func() (Vault, error) { return vault, nil }
+	t.Cleanup(func() { openVault = openFileVault })
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(reader)
+		output <- string(content)
+	}()
+
+	cmd := Builder()
+	cmd.SetArgs(args)
+	cmd.Execute()
+
+	writer.Close()
+	return <-output
+}
+
+func TestAddCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github", Password: "1234"})
+
+	output := runCommand(t, vault, "add", "acme", "-p", "p4$$w0rd", "-i", "acme.com page")
+	if !strings.Contains(output, "acme") {
+		t.Errorf("unexpected output %q", output)
+	}
+	data, err := vault.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Password != "p4$$w0rd" || data.Info != "acme.com page" {
+		t.Errorf("unexpected entry %+v", data)
+	}
+
+	runCommand(t, vault, "add", "random")
+	if data, _ := vault.Get("random"); len(data.Password) != 20 {
+		t.Errorf("expected a generated password, got %q", data.Password)
+	}
+
+	output = runCommand(t, vault, "add", "github", "-p", "other")
+	if !strings.Contains(output, "already exists") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if data, _ := vault.Get("github"); data.Password != "1234" {
+		t.Error("an existing entry must not be overwritten by add")
+	}
+}
+
+func TestEditCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github", Password: "1234", Info: "old"})
+
+	runCommand(t, vault, "edit", "github", "-i", "new")
+	if data, _ := vault.Get("github"); data.Password != "1234" || data.Info != "new" {
+		t.Errorf("unexpected entry %+v", data)
+	}
+
+	output := runCommand(t, vault, "edit", "gitlab", "-p", "1")
+	if !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestRichEntryFlags(t *testing.T) {
+	vault := newMemoryVault()
+
+	output := runCommand(t, vault, "add", "acme", "-p", "1234", "-u", "john", "--url", "https://acme.com", "--url", "https://admin.acme.com",
+		"-n", "support line", "-t", "work,billing", "-f", "hidden:pin=0000", "-f", "email:recovery=john@acme.com")
+	if !strings.Contains(output, "john") || !strings.Contains(output, "https://admin.acme.com") || strings.Contains(output, "0000") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	data, err := vault.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Username != "john" || len(data.URLs) != 2 || data.Notes != "support line" || !reflect.DeepEqual(data.Tags, []string{"work", "billing"}) || len(data.Fields) != 2 {
+		t.Errorf("unexpected entry %+v", data)
+	}
+	if data.CreatedAt.IsZero() || !data.UpdatedAt.Equal(data.CreatedAt) {
+		t.Errorf("expected creation timestamps, got %+v", data)
+	}
+
+	runCommand(t, vault, "edit", "acme", "-u", "jane", "-f", "pin=", "--url", "https://acme.org")
+	edited, _ := vault.Get("acme")
+	if edited.Username != "jane" || !reflect.DeepEqual(edited.URLs, []string{"https://acme.org"}) || len(edited.Fields) != 1 || edited.Notes != "support line" {
+		t.Errorf("unexpected entry %+v", edited)
+	}
+	if edited.UpdatedAt.Before(data.UpdatedAt) || !edited.CreatedAt.Equal(data.CreatedAt) {
+		t.Errorf("expected an updated timestamp, got %+v", edited)
+	}
+
+	output = runCommand(t, vault, "edit", "acme", "--url", "acme.com")
+	if !strings.Contains(output, "Invalid field") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if unchanged, _ := vault.Get("acme"); !reflect.DeepEqual(unchanged.URLs, edited.URLs) {
+		t.Errorf("an invalid flag must not change the entry, got %+v", unchanged)
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github", Password: "first"})
+
+	runCommand(t, vault, "edit", "github", "-p", "second")
+	runCommand(t, vault, "edit", "github", "-p", "third")
+
+	output := runCommand(t, vault, "history", "github")
+	if !strings.Contains(output, "second") || !strings.Contains(output, "first") || strings.Index(output, "second") > strings.Index(output, "first") {
+		t.Errorf("expected previous passwords newest first, got %q", output)
+	}
+
+	output = runCommand(t, vault, "edit", "github", "--revert", "1", "-p", "other")
+	if !strings.Contains(output, "--revert") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	runCommand(t, vault, "edit", "github", "--revert", "2")
+	data, _ := vault.Get("github")
+	if data.Password != "first" {
+		t.Errorf("expected the reverted password, got %q", data.Password)
+	}
+	if len(data.History) != 2 || data.History[0].Password != "third" || data.History[1].Password != "second" {
+		t.Errorf("unexpected history %+v", data.History)
+	}
+
+	output = runCommand(t, vault, "edit", "github", "--revert", "5")
+	if !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	output = runCommand(t, newMemoryVault(Data{Name: "aws"}), "history", "aws")
+	if !strings.Contains(output, "No previous passwords") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+// answer makes every confirmation question get the given answer
+func answer(t *testing.T, yes bool) {
+	original := confirm
+	confirm = func(string) bool { return yes }
+	t.Cleanup(func() { confirm = original })
+}
+
+func TestRemoveCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github"}, Data{Name: "gitlab"})
+
+	output := runCommand(t, vault, "remove", "github")
+	if !strings.Contains(output, "trash") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if _, err := vault.Get("github"); !errors.Is(err, entryNotFoundError) {
+		t.Errorf("expected github to be removed, got %v", err)
+	}
+	if _, err := vault.Get("gitlab"); err != nil {
+		t.Errorf("gitlab must be kept, got %v", err)
+	}
+
+	output = runCommand(t, vault, "remove", "github")
+	if !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	answer(t, false)
+	runCommand(t, vault, "remove", "gitlab", "--permanent")
+	if _, err := vault.Get("gitlab"); err != nil {
+		t.Errorf("a cancelled removal must keep the entry, got %v", err)
+	}
+
+	answer(t, true)
+	runCommand(t, vault, "remove", "gitlab", "--permanent")
+	if _, err := vault.Get("gitlab"); !errors.Is(err, entryNotFoundError) {
+		t.Errorf("expected gitlab to be removed, got %v", err)
+	}
+	if trashed, _ := vault.Trashed(); len(trashed) != 1 || trashed[0].Name != "github" {
+		t.Errorf("a permanent removal must skip the trash, got %+v", trashed)
+	}
+}
+
+func TestTrashCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github", Password: "1234"}, Data{Name: "gitlab"})
+	runCommand(t, vault, "remove", "github")
+	runCommand(t, vault, "remove", "gitlab")
+
+	output := runCommand(t, vault, "trash", "list")
+	if !strings.Contains(output, "github") || !strings.Contains(output, "gitlab") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	runCommand(t, vault, "trash", "restore", "github")
+	if data, err := vault.Get("github"); err != nil || data.Password != "1234" {
+		t.Errorf("expected github back, got %+v %v", data, err)
+	}
+
+	answer(t, true)
+	output = runCommand(t, vault, "trash", "purge", "--older-than", "24h")
+	if !strings.Contains(output, "empty") {
+		t.Errorf("nothing is that old, got %q", output)
+	}
+	runCommand(t, vault, "trash", "purge")
+	if trashed, _ := vault.Trashed(); len(trashed) != 0 {
+		t.Errorf("expected an empty trash, got %+v", trashed)
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github"}, Data{Name: "gitlab"}, Data{Name: "aws"})
+
+	output := runCommand(t, vault, "list", "git")
+	if !strings.Contains(output, "github") || !strings.Contains(output, "gitlab") || strings.Contains(output, "aws") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	output = runCommand(t, newMemoryVault(), "list")
+	if !strings.Contains(output, "No data stored") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestListFolder(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github"}, Data{Name: "work/aws/prod"}, Data{Name: "work/aws/dev"}, Data{Name: "work/gitlab"})
+
+	output := runCommand(t, vault, "list", "work/")
+	expected := []string{"├── \033[1maws/", "│   ├── \033[1mdev", "│   └── \033[1mprod", "│       └── \033[1minfo:", "└── \033[1mgitlab", "    └── \033[1minfo:"}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected %q in the tree, got %q", line, output)
+		}
+	}
+	if strings.Contains(output, "github") {
+		t.Errorf("entries outside the folder must not be shown, got %q", output)
+	}
+
+	output = runCommand(t, vault, "list", "personal/")
+	if !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestMoveCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "aws", Password: "1"}, Data{Name: "a/x"}, Data{Name: "a/b/x"}, Data{Name: "github"}, Data{Name: "personal/gitlab"})
+
+	names := func() []string {
+		items, _ := vault.List()
+		var names []string
+		for _, d := range items {
+			names = append(names, d.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	runCommand(t, vault, "mv", "aws", "work/aws/prod")
+	if data, err := vault.Get("work/aws/prod"); err != nil || data.Password != "1" {
+		t.Errorf("expected aws moved, got %+v %v", data, err)
+	}
+
+	runCommand(t, vault, "mv", "github", "personal/")
+	runCommand(t, vault, "mv", "a/", "a/b")
+	expected := []string{"a/b/b/x", "a/b/x", "personal/github", "personal/gitlab", "work/aws/prod"}
+	if !reflect.DeepEqual(names(), expected) {
+		t.Errorf("expected %v, got %v", expected, names())
+	}
+
+	output := runCommand(t, vault, "mv", "personal/github", "personal/gitlab")
+	if !strings.Contains(output, "already exists") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "mv", "nothing/", "other/")
+	if !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if !reflect.DeepEqual(names(), expected) {
+		t.Errorf("failed moves must change nothing, got %v", names())
+	}
+}
+
+func TestCompleteEntryNames(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github"}, Data{Name: "work/gitlab"})
+	openUnlockedVault = func() (Vault, error) { return vault, nil }
+	t.Cleanup(func() { openUnlockedVault = openUnlockedFileVault })
+
+	suggestions, directive := completeEntryNames(nil, nil, "")
+	if !reflect.DeepEqual(suggestions, []string{"github", "work/"}) || directive&cobra.ShellCompDirectiveNoSpace == 0 {
+		t.Errorf("unexpected completion %v %v", suggestions, directive)
+	}
+
+	openUnlockedVault = func() (Vault, error) { return nil, invalidPasswordError }
+	if suggestions, _ := completeEntryNames(nil, nil, ""); suggestions != nil {
+		t.Errorf("a locked vault must not be completed, got %v", suggestions)
+	}
+}
+
+func TestTagCommands(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "aws"}, Data{Name: "github"}, Data{Name: "gitlab"})
+
+	runCommand(t, vault, "tag", "add", "aws", "client-x", "rotate-q3")
+	runCommand(t, vault, "tag", "add", "github", "client-x", "shared")
+	runCommand(t, vault, "tag", "add", "gitlab", "shared")
+	runCommand(t, vault, "tag", "rm", "github", "shared")
+	if data, _ := vault.Get("github"); !reflect.DeepEqual(data.Tags, []string{"client-x"}) {
+		t.Errorf("unexpected tags %+v", data.Tags)
+	}
+
+	output := runCommand(t, vault, "tag", "add", "aws", "two words")
+	if !strings.Contains(output, "Invalid tag") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "tag", "add", "mail", "shared")
+	if !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	output = runCommand(t, vault, "tags")
+	if !strings.Contains(output, "client-x\033[0m 2") || !strings.Contains(output, "shared\033[0m 1") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	output = runCommand(t, vault, "list", "--tag", "client-x")
+	if !strings.Contains(output, "aws") || !strings.Contains(output, "github") || strings.Contains(output, "gitlab") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "list", "--tag", "nobody")
+	if !strings.Contains(output, "No entries tagged") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	t.Chdir(t.TempDir())
+	runCommand(t, vault, "export", "--tag", "client-x", "--tag", "rotate-q3")
+	exported, err := getDataSliceFromJsonFile(passcExportFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 1 || exported[0].Name != "aws" {
+		t.Errorf("expected only aws exported, got %+v", exported)
+	}
+}
+
+func TestWeakPasswordWarning(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github", Password: "Xk9$mQ2vLp3@wR7n"})
+
+	output := runCommand(t, vault, "add", "acme", "-p", "password")
+	if !strings.Contains(output, "Weak password") || !strings.Contains(output, "common password") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if data, _ := vault.Get("acme"); data.Password != "password" {
+		t.Error("a weak password is only a warning")
+	}
+
+	output = runCommand(t, vault, "edit", "github", "-p", "github")
+	if !strings.Contains(output, "Weak password") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "edit", "github", "-p", "correcthorsebatterystaple")
+	if strings.Contains(output, "Weak password") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "add", "random")
+	if strings.Contains(output, "Weak password") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestAuditCommand(t *testing.T) {
+	status := 0
+	exit = func(code int) { status = code }
+	t.Cleanup(func() { exit = os.Exit })
+
+	vault := newMemoryVault(
+		Data{Name: "github", Password: "1234", Tags: []string{"work"}},
+		Data{Name: "gitlab", Password: "1234"},
+		Data{Name: "mail", Password: "Xk9$mQ2vLp3@wR7n", Username: "john", URLs: []string{"https://mail.com"}, Tags: []string{"personal"}},
+	)
+
+	output := runCommand(t, vault, "audit")
+	if !strings.Contains(output, "github, gitlab") || !strings.Contains(output, "findings") || status != 1 {
+		t.Errorf("unexpected output %q, status %d", output, status)
+	}
+
+	status = 0
+	output = runCommand(t, vault, "audit", "--json", "--fail-on", "none")
+	var report auditReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if report.Entries != 3 || report.Failed || status != 0 || report.Findings[0].Check != checkReused {
+		t.Errorf("unexpected report %+v, status %d", report, status)
+	}
+
+	// mail has no dates yet, only its age is unknown
+	output = runCommand(t, vault, "audit", "--tag", "personal", "--json")
+	report = auditReport{}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if report.Entries != 1 || len(report.Findings) != 1 || report.Findings[0].Check != checkUnknownAge || status != 0 {
+		t.Errorf("unexpected report %+v, status %d", report, status)
+	}
+
+	output = runCommand(t, vault, "audit", "--fail-on", "urgent")
+	if !strings.Contains(output, "severity") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestBreachedPasswordCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	exit = func(int) {}
+	t.Cleanup(func() { exit = os.Exit })
+
+	source := writeHashFile(t, 100)
+	output := runCommand(t, newMemoryVault(), "breachdb", "build", source)
+	if !strings.Contains(output, "105 hashes") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	// add and edit use the default bloom filter once it is built
+	vault := newMemoryVault(Data{Name: "github", Password: "Xk9$mQ2vLp3@wR7n"})
+	output = runCommand(t, vault, "add", "acme", "-p", "letmein")
+	if !strings.Contains(output, "breached passwords list") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "edit", "github", "-p", "Yp4&nR8zQw2!kT6m")
+	if strings.Contains(output, "breached") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	// the sorted file also knows how many times a password was seen
+	output = runCommand(t, vault, "audit", "--json", "--breach-db", source)
+	var report auditReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", output, err)
+	}
+	if report.Findings[0].Check != checkBreached || report.Findings[0].Entries[0] != "acme" || report.Findings[0].Detail != "seen 4 times in data breaches" {
+		t.Errorf("unexpected findings %+v", report.Findings)
+	}
+}
+
+// writeProfiles sets up a HOME whose config.json has generator profiles
+func writeProfiles(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, passcDirFolder)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"profiles": {
+		"pin": {"length": 6, "lower": false, "upper": false, "symbols": false},
+		"bank": {"length": 16, "symbols": false, "exclude": "aeiou"},
+		"master": {"passphrase": {"words": 4, "separator": " "}}
+	}}`
+	if err := os.WriteFile(filepath.Join(dir, passcConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordCommand(t *testing.T) {
+	output := strings.TrimSpace(runCommand(t, newMemoryVault(), "password"))
+	if len(output) != 20 {
+		t.Errorf("unexpected password %q", output)
+	}
+
+	output = strings.TrimSpace(runCommand(t, newMemoryVault(), "password", "12", "--symbols", "-_", "--min-symbols", "4", "--no-ambiguous"))
+	if len(output) != 12 || strings.Count(output, "-")+strings.Count(output, "_") < 4 || strings.ContainsAny(output, passcCharsetAmbiguous) {
+		t.Errorf("unexpected password %q", output)
+	}
+
+	output = runCommand(t, newMemoryVault(), "password", "3", "--min-digits", "4")
+	if !strings.Contains(output, "Invalid password policy") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestPasswordProfiles(t *testing.T) {
+	writeProfiles(t)
+
+	output := strings.TrimSpace(runCommand(t, newMemoryVault(), "password", "--profile", "pin"))
+	if len(output) != 6 || !isDigits(output) {
+		t.Errorf("unexpected password %q", output)
+	}
+	// the number replaces the length of the profile
+	output = strings.TrimSpace(runCommand(t, newMemoryVault(), "password", "8", "--profile", "pin"))
+	if len(output) != 8 || !isDigits(output) {
+		t.Errorf("unexpected password %q", output)
+	}
+	// and flags change the rest of it
+	output = strings.TrimSpace(runCommand(t, newMemoryVault(), "password", "--profile", "bank", "--min-digits", "3"))
+	digits := 0
+	for _, r := range output {
+		if strings.ContainsRune(passcCharsetNumeric, r) {
+			digits++
+		}
+	}
+	if len(output) != 16 || digits < 3 || strings.ContainsAny(output, "aeiou"+passcCharsetSymbols) {
+		t.Errorf("unexpected password %q", output)
+	}
+
+	output = strings.TrimSpace(runCommand(t, newMemoryVault(), "password", "5", "--profile", "master"))
+	if strings.Count(output, " ") != 4 {
+		t.Errorf("unexpected passphrase %q", output)
+	}
+	if output := runCommand(t, newMemoryVault(), "password", "--profile", "master", "--no-repeats"); !strings.Contains(output, passcProfileFlagsErr) {
+		t.Errorf("unexpected output %q", output)
+	}
+	if output := runCommand(t, newMemoryVault(), "password", "--profile", "shop"); !strings.Contains(output, "shop") || !strings.Contains(output, "not found") {
+		t.Errorf("unexpected output %q", output)
+	}
+}
+
+func TestEntryProfiles(t *testing.T) {
+	writeProfiles(t)
+	vault := newMemoryVault()
+
+	runCommand(t, vault, "add", "card", "--profile", "pin")
+	card, err := vault.Get("card")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Password) != 6 || !isDigits(card.Password) {
+		t.Errorf("unexpected password %q", card.Password)
+	}
+
+	runCommand(t, vault, "edit", "card", "--regenerate", "--profile", "master")
+	card, _ = vault.Get("card")
+	if strings.Count(card.Password, " ") != 3 || len(card.History) != 1 || !isDigits(card.History[0].Password) {
+		t.Errorf("unexpected entry %+v", card)
+	}
+
+	runCommand(t, vault, "edit", "card", "--regenerate")
+	if data, _ := vault.Get("card"); len(data.Password) != 20 || len(data.History) != 2 {
+		t.Errorf("unexpected entry %+v", data)
+	}
+
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"add", "bank", "--profile", "bank", "-p", "1234"}, passcProfilePasswordErr},
+		{[]string{"add", "bank", "--profile", "bank", "--passphrase"}, passcProfilePassphraseErr},
+		{[]string{"add", "bank", "--profile", "shop"}, "not found"},
+		{[]string{"edit", "card", "--profile", "pin"}, passcProfileRegenerateErr},
+		{[]string{"edit", "card", "--regenerate", "--revert", "1"}, passcRegenerateErr},
+	} {
+		if output := runCommand(t, vault, test.args...); !strings.Contains(output, test.expected) {
+			t.Errorf("%v: unexpected output %q", test.args, output)
+		}
+	}
+	if _, err := vault.Get("bank"); !errors.Is(err, entryNotFoundError) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPassphraseCommands(t *testing.T) {
+	output := strings.TrimSpace(runCommand(t, newMemoryVault(), "passphrase", "4", "--separator", "."))
+	if strings.Count(output, ".") != 3 {
+		t.Errorf("unexpected passphrase %q", output)
+	}
+
+	vault := newMemoryVault()
+	runCommand(t, vault, "add", "laptop", "--passphrase")
+	if data, _ := vault.Get("laptop"); strings.Count(data.Password, "-") < 5 {
+		t.Errorf("expected a passphrase, got %q", data.Password)
+	}
+	runCommand(t, vault, "add", "server", "--passphrase", "-p", "Xk9$mQ2vLp3@wR7n")
+	if data, _ := vault.Get("server"); data.Password != "Xk9$mQ2vLp3@wR7n" {
+		t.Errorf("-p must win over --passphrase, got %q", data.Password)
+	}
+}
+
+func TestOTPCommand(t *testing.T) {
+	// the RFC 4226 secret "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	vault := newMemoryVault(Data{Name: "github"})
+
+	runCommand(t, vault, "add", "bank", "--otp", secret, "--otp-type", "hotp")
+	output := runCommand(t, vault, "otp", "bank")
+	if !strings.Contains(output, "755224") || !strings.Contains(output, "counter 0") {
+		t.Errorf("unexpected output %q", output)
+	}
+	output = runCommand(t, vault, "otp", "bank")
+	if !strings.Contains(output, "287082") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if data, _ := vault.Get("bank"); data.OTP.Counter != 2 {
+		t.Errorf("the hotp counter must be saved after each code, got %d", data.OTP.Counter)
+	}
+
+	runCommand(t, vault, "edit", "github", "--otp", "otpauth://totp/GitHub:john?secret="+secret)
+	output = runCommand(t, vault, "otp", "github")
+	if !strings.Contains(output, "s left") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if data, _ := vault.Get("github"); data.OTP.Issuer != "GitHub" || data.OTP.Account != "john" {
+		t.Errorf("unexpected otp %+v", data.OTP)
+	}
+
+	runCommand(t, vault, "edit", "github", "--otp", "")
+	output = runCommand(t, vault, "otp", "github")
+	if !strings.Contains(output, "no one-time password") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	output = runCommand(t, vault, "add", "mail", "--otp", "not base32!")
+	if !strings.Contains(output, "Invalid one-time password") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if _, err := vault.Get("mail"); !errors.Is(err, entryNotFoundError) {
+		t.Error("an entry with an invalid otp must not be added")
+	}
+}
+
+func TestImportCommand(t *testing.T) {
+	vault := newMemoryVault(Data{Name: "github"})
+	file, err := os.CreateTemp(t.TempDir(), "import.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`[{"name":"gitlab","password":"1"},{"name":"aws","password":"2"}]`)
+	file.Close()
+
+	runCommand(t, vault, "import", file.Name())
+	if items, _ := vault.List(); len(items) != 3 {
+		t.Errorf("expected 3 entries, got %+v", items)
+	}
+
+	output := runCommand(t, vault, "import", file.Name())
+	if !strings.Contains(output, "could not be imported") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	for _, name := range []string{"work/", "/x", "a//b", ""} {
+		invalid := filepath.Join(t.TempDir(), "invalid.json")
+		content, _ := json.Marshal([]Data{{Name: "gitea", Password: "3"}, {Name: name, Password: "4"}})
+		if err := os.WriteFile(invalid, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		output := runCommand(t, vault, "import", invalid)
+		if !strings.Contains(output, "Invalid name") {
+			t.Errorf("%q: unexpected output %q", name, output)
+		}
+		if items, _ := vault.List(); len(items) != 3 {
+			t.Errorf("%q: nothing must be imported, got %+v", name, items)
+		}
+	}
+}
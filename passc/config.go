@@ -0,0 +1,151 @@
+package passc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Vault   string        `json:"vault"`
+	KDF     KDFParams     `json:"kdf"`
+	Session SessionConfig `json:"session"`
+	Backup  BackupConfig  `json:"backup"`
+	Audit   AuditConfig   `json:"audit"`
+	// HistoryDepth is the number of previous passwords kept per entry
+	HistoryDepth int `json:"historyDepth"`
+	// MinPasswordScore warns on add and edit below this strength, from 0 (never) to 4
+	MinPasswordScore int `json:"minPasswordScore"`
+	// BreachDB is the bloom filter or sorted SHA-1 file of breached passwords. Empty uses the
+	// one built by passc breachdb build, if any
+	BreachDB string `json:"breachDB"`
+	// Profiles are generator presets used with --profile
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// SessionConfig bounds how long the agent keeps a store unlocked. Zero disables a limit
+type SessionConfig struct {
+	IdleTimeout Duration `json:"idleTimeout"`
+	MaxLifetime Duration `json:"maxLifetime"`
+}
+
+// BackupConfig is the retention of backup generations. The newest one is always kept
+type BackupConfig struct {
+	// Keep is the number of generations kept, 0 keeps them all
+	Keep int `json:"keep"`
+	// MaxAge deletes older generations, 0 keeps them whatever their age
+	MaxAge Duration `json:"maxAge"`
+}
+
+// AuditConfig tunes passc audit
+type AuditConfig struct {
+	// MaxAge flags passwords not changed for longer, 0 disables the check
+	MaxAge Duration `json:"maxAge"`
+	// FailOn is the lowest severity making passc audit exit with an error: low, medium, high or none
+	FailOn string `json:"failOn"`
+	// Severities overrides the severity of a check, "none" skips it
+	Severities map[string]string `json:"severities"`
+}
+
+// Duration reads values like "15m" or "8h" from the config file
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %v", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration %q must not be negative", value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func defaultConfig() Config {
+	return Config{
+		KDF: defaultKDFParams(),
+		Session: SessionConfig{
+			IdleTimeout: Duration(15 * time.Minute),
+			MaxLifetime: Duration(8 * time.Hour),
+		},
+		Backup: BackupConfig{
+			Keep:   10,
+			MaxAge: Duration(90 * 24 * time.Hour),
+		},
+		Audit: AuditConfig{
+			MaxAge: Duration(365 * 24 * time.Hour),
+			FailOn: severityHigh,
+		},
+		HistoryDepth:     10,
+		MinPasswordScore: 3,
+	}
+}
+
+// loadConfig reads $HOME/.passcualito/config.json. Missing file or missing keys fall back to defaults
+func loadConfig() (*Config, error) {
+	config := defaultConfig()
+
+	dir, err := passcDir()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, passcConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &config, nil
+		}
+		return nil, fmt.Errorf("reading config: %v", err)
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("parsing config: %v", err)
+	}
+
+	if err := config.KDF.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if config.Backup.Keep < 0 {
+		return nil, fmt.Errorf("invalid config: backup keep must not be negative")
+	}
+	if config.HistoryDepth < 0 {
+		return nil, fmt.Errorf("invalid config: history depth must not be negative")
+	}
+	if err := config.Audit.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if config.MinPasswordScore < 0 || config.MinPasswordScore > 4 {
+		return nil, fmt.Errorf("invalid config: min password score must be between 0 and 4")
+	}
+	for name, profile := range config.Profiles {
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config: profile %q: %s", name, strings.TrimPrefix(err.Error(), passcPolicyErr+": "))
+		}
+	}
+
+	return &config, nil
+}
+
+// profile returns the generator profile called name, the default password policy when name is empty
+func (c *Config) profile(name string) (Profile, error) {
+	if name == "" {
+		return defaultProfile(), nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf(passcProfileNotFoundErr, name)
+	}
+	return profile, nil
+}
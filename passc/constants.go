@@ -4,12 +4,26 @@ const (
 	passcAppCommandName           = "passc"
 	passcInitTitle                = "\033[1m  Passcualito Init\033[0m"
 	passcLoginTitle               = "\033[1m  Passcualito Login\033[0m"
+	passcChangePasswordTitle      = "\033[1m  Passcualito Master Password\033[0m"
 	passcStoreTitle               = "\033[1m󰪶  Storage\033[0m"
 	passcMatchTitle               = "\033[1m󰪶  Matches\033[0m"
+	passcVaultsTitle              = "\033[1m󰪶  Vaults\033[0m"
+	passcBackupsTitle             = "\033[1m󰪶  Backups\033[0m"
+	passcHistoryTitle             = "\033[1m  History of %s\033[0m\n"
+	passcTrashTitle               = "\033[1m󰪶  Trash\033[0m"
+	passcFolderTitle              = "\033[1m󰪶  %s\033[0m\n"
+	passcTagsTitle                = "\033[1m󰪶  Tags\033[0m"
+	passcAuditTitle               = "\033[1m󰪶  Audit of %d entries\033[0m\n"
 	passcMasterPasswordText       = "  Master Password: "
+	passcOldMasterPasswordText    = "  Current Master Password: "
+	passcNewMasterPasswordText    = "  New Master Password: "
+	passcConfirmPasswordText      = "  Confirm New Master Password: "
+	passcMasterPasswordChanged    = "󰸞  Master Password changed. Log in again with the new one"
 	passcEmptyFile                = "󱀰  No data stored"
 	passcInvalidPassword          = "  Invalid Password"
 	passcLogoutText               = "󰍃  Logged out"
+	passcLockedText               = "  Vault is locked"
+	passcUnlockedText             = "  Vault is unlocked"
 	passcNameNotFoundText         = "󰮗  Name \033[1m%s\033[0m not found\n"
 	passcNameTakenText            = "  Name \033[1m%s\033[0m already exists\n"
 	passcEntryCreatedText         = "󰸞  Entry \033[1m%s\033[0m created\n"
@@ -21,19 +35,80 @@ const (
 	passcPasswordParamErrNumber   = "  Parameter must be a number"
 	passcPasswordParamErrPositive = "  Parameter must be a positive number"
 	passcExportErr                = "  Error exporting data"
+	passcUnknownFormatErr         = "  Unrecognized store format. Stores created by older versions need \033[1mpassc migrate\033[0m"
+	passcUnsupportedVersionErr    = "  Unsupported store version"
+	passcTruncatedErr             = "  Store file is truncated"
+	passcTamperedErr              = "  Store file is corrupted or has been tampered with"
 	passcMasterPasswordLenErr     = "  Master Password must have at least 6 characters"
+	passcAgentRunningErr          = "  Agent is already running"
+	passcVaultNotFoundErr         = "  Vault not found"
+	passcVaultExistsErr           = "  Vault already exists"
+	passcVaultNameErr             = "  Invalid vault name (letters, digits, '.', '_' and '-')"
+	passcVaultBusyErr             = "  Vault is busy: another passc command is changing it. Try again"
+	passcBackupNotFoundErr        = "󰮗  Backup not found"
+	passcHistoryNotFoundErr       = "󰮗  Previous password not found"
+	passcRevertPasswordErr        = "  --revert can not be used together with --password"
+	passcFieldErr                 = "  Invalid field, use [type:]name=value"
+	passcEntryNameErr             = "  Invalid name: use folder/name without empty parts"
+	passcTagErr                   = "  Invalid tag: no spaces or commas"
+	passcOTPErr                   = "  Invalid one-time password"
+	passcBreachDBErr              = "  Breached passwords database error"
+	passcPolicyErr                = "  Invalid password policy"
+	passcProfileNotFoundErr       = "󰮗  Profile \033[1m%s\033[0m not found in config.json"
+	passcProfilePasswordErr       = "  --profile can not be used together with --password"
+	passcProfilePassphraseErr     = "  --profile can not be used together with --passphrase"
+	passcRegenerateErr            = "  --regenerate can not be used together with --password or --revert"
+	passcProfileRegenerateErr     = "  --profile needs --regenerate to change the password"
+	passcProfileFlagsErr          = "  Character flags do not apply to a passphrase profile"
+	passcNoOTPErr                 = "󰮗  Entry \033[1m%s\033[0m has no one-time password. Add one with \033[1mpassc edit %s --otp\033[0m\n"
+	passcPasswordMismatchErr      = "  Passwords do not match"
+	passcSamePasswordErr          = "  New Master Password must be different from the current one"
+	passcMasterPasswordWeakErr    = "  Master Password needs 10 characters of 3 kinds (lower, upper, digits, symbols) or 16 characters of any kind"
 	passcExportText               = "󰸞  Data exported to \033[1m %s\033[0m\n"
+	passcMigrateText              = "󰸞  Migrated %d entries. Legacy store kept at \033[1m%s\033[0m\n"
+	passcMigrateUpToDateText      = "󰸞  Store is already in the current format"
+	passcVaultCreatedText         = "󰸞  Vault \033[1m%s\033[0m created. Use it with \033[1mpassc vault use %s\033[0m\n"
+	passcVaultUsedText            = "󰸞  Using vault \033[1m%s\033[0m\n"
+	passcBackupCreatedText        = "󰸞  Backup \033[1m%s\033[0m created\n"
+	passcBackupRestoredText       = "󰸞  Backup \033[1m%s\033[0m restored. The replaced store was kept as backup \033[1m%s\033[0m\n"
+	passcNoBackupsText            = "󱀰  No backups"
+	passcNoHistoryText            = "󱀰  No previous passwords for \033[1m%s\033[0m\n"
+	passcEntryTrashedText         = "󰸞  Entry \033[1m%s\033[0m moved to the trash. Undo with \033[1mpassc trash restore %s\033[0m\n"
+	passcEntryRestoredText        = "󰸞  Entry \033[1m%s\033[0m restored\n"
+	passcEntryMovedText           = "󰸞  Moved \033[1m%s\033[0m to \033[1m%s\033[0m (%d entries)\n"
+	passcTagsAddedText            = "󰸞  Entry \033[1m%s\033[0m tagged with \033[1m%s\033[0m\n"
+	passcTagsRemovedText          = "󰸞  Tags \033[1m%s\033[0m removed from \033[1m%s\033[0m\n"
+	passcNoTagsText               = "󱀰  No tags"
+	passcNoTaggedText             = "󱀰  No entries tagged \033[1m%s\033[0m\n"
+	passcAuditSummaryText         = "  %d findings: %d high, %d medium, %d low, %d info\n"
+	passcAuditCleanText           = "󰸞  No findings"
+	passcTOTPText                 = "  %s  (%ds left)\n"
+	passcHOTPText                 = "  %s  (counter %d)\n"
+	passcOTPCopiedText            = "󰢨  Copied the one-time password of \033[1m%s\033[0m to clipboard (%ds left)\n"
+	passcWeakPasswordText         = "  Weak password for \033[1m%s\033[0m: score %d/4, cracked in %s\n"
+	passcBreachedPasswordText     = "  The password of \033[1m%s\033[0m was %s\n"
+	passcBreachDBBuiltText        = "󰸞  Bloom filter of %d hashes written to \033[1m%s\033[0m\n"
+	passcStrengthHintText         = "    %s\n"
+	passcEntropyText              = "  %.1f bits of entropy\n"
+	passcTrashPurgedText          = "󰸞  %d entries purged from the trash\n"
+	passcTrashEmptyText           = "󱀰  Trash is empty"
+	passcConfirmRemoveText        = "  Permanently remove \033[1m%s\033[0m? This can not be undone [y/N] "
+	passcConfirmPurgeText         = "  Permanently delete %d trashed entries? This can not be undone [y/N] "
+	passcCancelledText            = "  Cancelled"
 	passcVersion                  = "\033[1mPasscualito\033[0m v0.1.0"
 	passcDirFolder                = ".passcualito"
 	passcStoreFile                = "store.passc"
 	passcBackUpFile               = "store.bkp"
-	passcPathStoreFile            = passcDirFolder + "/" + passcStoreFile
-	passcExtension                = ".passc"
+	passcBackupsExtension         = ".backups"
+	passcConfigFile               = "config.json"
+	passcVaultsFolder             = "vaults"
+	passcStoreEnv                 = "PASSC_STORE"
+	passcAgentSocket              = "agent.sock"
 	passcExportFilename           = "passcualito.json"
 	passcItemSeparator            = "|"
 	passcCharsetNumeric           = "0123456789"
 	passcCharsetAlpha             = "abcdefghijklmnopqrstuvwxyz"
-	passcCharsetAlphaNumeric      = passcCharsetAlpha + passcCharsetNumeric
-	passcCharsetAlphaNumericCap   = passcCharsetAlpha + passcCharsetNumeric + "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	passcCharset                  = passcCharsetAlpha + passcCharsetNumeric + passcCharsetAlphaNumericCap + "!@#$%^&*()"
+	passcCharsetUpper             = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passcCharsetSymbols           = "!@#$%^&*()"
+	passcCharsetAmbiguous         = "0O1lI"
 )
@@ -2,19 +2,39 @@ package passc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/javiorfo/steams/v2"
 )
 
 type Data struct {
-	Name     string `json:"name"`
-	Password string `json:"password"`
-	Info     string `json:"info"`
+	Name      string    `json:"name"`
+	Password  string    `json:"password"`
+	Info      string    `json:"info"`
+	Username  string    `json:"username,omitempty"`
+	URLs      []string  `json:"urls,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Fields    []Field   `json:"fields,omitempty"`
+	OTP       *OTP      `json:"otp,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+	UpdatedAt time.Time `json:"updatedAt,omitzero"`
+	// History keeps the previous passwords, newest first
+	History []PasswordChange `json:"history,omitempty"`
 }
 
+// PasswordChange is a password that was replaced and when it happened
+type PasswordChange struct {
+	Password  string    `json:"password"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+var historyNotFoundError = errors.New(passcHistoryNotFoundErr)
+
 func newData(name, password, info string) (*Data, error) {
 	data := Data{
 		Name: name,
@@ -22,11 +42,11 @@ func newData(name, password, info string) (*Data, error) {
 	}
 
 	if password == "" {
-		pwd, err := generateRandomPasswordDefault()
+		pwd, err := generatePassword(defaultPolicy())
 		if err != nil {
 			return nil, err
 		}
-		password = *pwd
+		password = pwd
 	}
 	data.Password = password
 	return &data, nil
@@ -50,15 +70,100 @@ func (d *Data) fromJSON(jsonStr []byte) error {
 	return nil
 }
 
+// setPassword replaces the password keeping the previous one in the history,
+// which never grows beyond depth entries (0 keeps no history)
+func (d *Data) setPassword(password string, depth int, now time.Time) {
+	if password == d.Password {
+		return
+	}
+	d.History = append([]PasswordChange{{Password: d.Password, ChangedAt: now}}, d.History...)
+	d.Password = password
+	d.trimHistory(depth)
+}
+
+// passwordChangedAt is when the current password was set: the date of the last change kept in the
+// history or the creation date. It is zero for entries created before dates were kept
+func (d Data) passwordChangedAt() time.Time {
+	if len(d.History) > 0 {
+		return d.History[0].ChangedAt
+	}
+	return d.CreatedAt
+}
+
+// revertPassword restores the nth previous password (1 is the most recent one).
+// The replaced password takes its place at the top of the history
+func (d *Data) revertPassword(n int, depth int, now time.Time) error {
+	if n < 1 || n > len(d.History) {
+		return fmt.Errorf("%w: %d", historyNotFoundError, n)
+	}
+	previous := d.History[n-1].Password
+	d.History = append(d.History[:n-1:n-1], d.History[n:]...)
+	d.setPassword(previous, depth, now)
+	return nil
+}
+
+func (d *Data) trimHistory(depth int) {
+	if len(d.History) > depth {
+		d.History = d.History[:depth]
+	}
+	if len(d.History) == 0 {
+		d.History = nil
+	}
+}
+
 func (d Data) print(isEnd bool) {
+	rows := d.rows()
 	fmt.Println("│")
-	fmt.Println("├── \033[1mname:\033[0m    ", d.Name)
-	fmt.Println("├── \033[1mpassword:\033[0m", d.Password)
-	if isEnd {
-		fmt.Println("└── \033[1minfo:\033[0m    ", d.Info)
-	} else {
-		fmt.Println("├── \033[1minfo:\033[0m    ", d.Info)
+	for i, row := range rows {
+		branch := "├──"
+		if isEnd && i == len(rows)-1 {
+			branch = "└──"
+		}
+		fmt.Printf("%s \033[1m%-9s\033[0m %s\n", branch, row[0]+":", row[1])
+	}
+}
+
+// printRows renders the properties as the last level of a tree drawn with prefix
+func (d Data) printRows(prefix string) {
+	rows := d.rows()
+	for i, row := range rows {
+		branch, _ := treeBranch(prefix, i == len(rows)-1)
+		fmt.Printf("%s\033[1m%-9s\033[0m %s\n", branch, row[0]+":", row[1])
+	}
+}
+
+// rows are the label and value of every property worth showing
+func (d Data) rows() [][2]string {
+	rows := [][2]string{
+		{"name", d.Name},
+		{"password", d.Password},
+		{"info", d.Info},
+	}
+	if d.Username != "" {
+		rows = append(rows, [2]string{"username", d.Username})
+	}
+	for _, u := range d.URLs {
+		rows = append(rows, [2]string{"url", u})
+	}
+	if d.Notes != "" {
+		rows = append(rows, [2]string{"notes", d.Notes})
+	}
+	if len(d.Tags) != 0 {
+		rows = append(rows, [2]string{"tags", strings.Join(d.Tags, ", ")})
+	}
+	for _, field := range d.Fields {
+		rows = append(rows, [2]string{field.Name, field.display()})
+	}
+	if d.OTP != nil {
+		rows = append(rows, [2]string{"otp", d.OTP.String()})
 	}
+	if !d.CreatedAt.IsZero() {
+		rows = append(rows, [2]string{"created", d.CreatedAt.Local().Format("2006-01-02 15:04:05")})
+	}
+	if !d.UpdatedAt.IsZero() {
+		rows = append(rows, [2]string{"updated", d.UpdatedAt.Local().Format("2006-01-02 15:04:05")})
+	}
+	return rows
 }
 
 func (d Data) isNameMatch(inputName string) bool {
@@ -71,24 +176,89 @@ func (d Data) isNameMatch(inputName string) bool {
 	return isContained
 }
 
-func stringToDataSlice(content string) []Data {
-	items := strings.Split(content, passcItemSeparator)
-	return steams.Map(steams.FromSlice(items), func(v string) Data {
-		var data Data
-		err := data.fromJSON([]byte(v))
-		_ = err // Unimplemented
-		return data
-	}).Collect()
+// vaultDocument is the plaintext kept inside the encrypted store
+type vaultDocument struct {
+	Version int            `json:"version"`
+	Entries []Data         `json:"entries"`
+	Trash   []TrashedEntry `json:"trash,omitempty"`
+}
+
+// TrashedEntry is a removed entry that can still be restored
+type TrashedEntry struct {
+	Data
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+const documentVersion = 1
+
+func encodeDocument(document vaultDocument) (string, error) {
+	document.Version = documentVersion
+	if document.Entries == nil {
+		document.Entries = []Data{}
+	}
+	content, err := json.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// decodeDocument reads the store plaintext. Stores written before the document format
+// hold JSON objects joined by "|" and are still understood
+func decodeDocument(content string) (vaultDocument, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return vaultDocument{Version: documentVersion, Entries: []Data{}}, nil
+	}
+
+	var document vaultDocument
+	if err := json.Unmarshal([]byte(content), &document); err == nil && document.Version != 0 {
+		if document.Version > documentVersion {
+			return vaultDocument{}, fmt.Errorf("%w: entries version %d", unsupportedVersionError, document.Version)
+		}
+		if document.Entries == nil {
+			document.Entries = []Data{}
+		}
+		return document, nil
+	}
+
+	items, err := decodeLegacyEntries(content)
+	if err != nil {
+		return vaultDocument{}, err
+	}
+	return vaultDocument{Version: documentVersion, Entries: items}, nil
+}
+
+func encodeEntries(items []Data) (string, error) {
+	return encodeDocument(vaultDocument{Entries: items})
 }
 
-func isNameTaken(content, name string) bool {
-	items := strings.Split(content, passcItemSeparator)
-	return steams.FromSlice(items).Any(func(v string) bool {
+func decodeEntries(content string) ([]Data, error) {
+	document, err := decodeDocument(content)
+	return document.Entries, err
+}
+
+// decodeLegacyEntries decodes whole JSON objects one after the other, so a "|"
+// inside a value is never taken as a separator
+func decodeLegacyEntries(content string) ([]Data, error) {
+	items := []Data{}
+	for {
+		decoder := json.NewDecoder(strings.NewReader(content))
 		var data Data
-		err := data.fromJSON([]byte(v))
-		_ = err
-		return data.Name == name
-	})
+		if err := decoder.Decode(&data); err != nil {
+			return nil, fmt.Errorf("decoding entries: %v", err)
+		}
+		items = append(items, data)
+
+		content = strings.TrimSpace(content[decoder.InputOffset():])
+		if content == "" {
+			return items, nil
+		}
+		if !strings.HasPrefix(content, passcItemSeparator) {
+			return nil, fmt.Errorf("decoding entries: unexpected %q", content[:1])
+		}
+		content = content[len(passcItemSeparator):]
+	}
 }
 
 func getDataSliceFromJsonFile(filePath string) ([]Data, error) {
@@ -2,10 +2,12 @@ package passc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestData(t *testing.T) {
@@ -29,6 +31,51 @@ func TestData(t *testing.T) {
 	t.Logf("Data from JSON: %#v", data)
 }
 
+func TestPasswordHistory(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	data := Data{Name: "github", Password: "1"}
+
+	data.setPassword("1", 3, now)
+	if len(data.History) != 0 {
+		t.Error("setting the same password must not add history")
+	}
+
+	for i, password := range []string{"2", "3", "4", "5"} {
+		data.setPassword(password, 3, now.Add(time.Duration(i)*time.Hour))
+	}
+	expected := []PasswordChange{
+		{Password: "4", ChangedAt: now.Add(3 * time.Hour)},
+		{Password: "3", ChangedAt: now.Add(2 * time.Hour)},
+		{Password: "2", ChangedAt: now.Add(time.Hour)},
+	}
+	if data.Password != "5" || !reflect.DeepEqual(data.History, expected) {
+		t.Errorf("expected 5 with history %+v, got %s %+v", expected, data.Password, data.History)
+	}
+
+	if err := data.revertPassword(2, 3, now.Add(4*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	expected = []PasswordChange{
+		{Password: "5", ChangedAt: now.Add(4 * time.Hour)},
+		{Password: "4", ChangedAt: now.Add(3 * time.Hour)},
+		{Password: "2", ChangedAt: now.Add(time.Hour)},
+	}
+	if data.Password != "3" || !reflect.DeepEqual(data.History, expected) {
+		t.Errorf("expected 3 with history %+v, got %s %+v", expected, data.Password, data.History)
+	}
+
+	for _, n := range []int{0, 4, -1} {
+		if err := data.revertPassword(n, 3, now); !errors.Is(err, historyNotFoundError) {
+			t.Errorf("%d: expected history not found, got %v", n, err)
+		}
+	}
+
+	data.setPassword("6", 0, now)
+	if data.History != nil {
+		t.Errorf("a depth of 0 keeps no history, got %+v", data.History)
+	}
+}
+
 func TestGetDataSliceFromJsonFile(t *testing.T) {
 	tempFile, err := os.CreateTemp("", "test_data.json")
 	if err != nil {
@@ -65,84 +112,133 @@ func TestGetDataSliceFromJsonFile(t *testing.T) {
 	}
 }
 
-func TestStringToDataSlice(t *testing.T) {
+// awkward holds every character that broke the old "|" separated records
+var awkward = Data{
+	Name:     `a|b "quoted" \ name ñandú 🔑`,
+	Password: `p|a"ss\w|rd\n{"name":"x"}`,
+	Info:     `info | "with" \u0000 ünïcödé 日本`,
+}
+
+func TestEncodeEntries(t *testing.T) {
+	items := []Data{awkward, {Name: "John", Password: "other value"}}
+
+	content, err := encodeEntries(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeEntries(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, items) {
+		t.Errorf("expected %+v, got %+v", items, decoded)
+	}
+
+	if _, err := decodeEntries(`{"version":99,"entries":[]}`); !errors.Is(err, unsupportedVersionError) {
+		t.Errorf("expected unsupported version, got %v", err)
+	}
+}
+
+func TestDecodeEntries(t *testing.T) {
 	tests := []struct {
 		name     string
 		content  string
 		expected []Data
 	}{
 		{
-			name:    "single item",
+			name:     "empty",
+			content:  "",
+			expected: []Data{},
+		},
+		{
+			name:     "empty document",
+			content:  `{"version":1,"entries":[]}`,
+			expected: []Data{},
+		},
+		{
+			name:    "single legacy item",
 			content: `{"name":"John","password":"other value"}`,
 			expected: []Data{
 				{Name: "John", Password: "other value"},
 			},
 		},
 		{
-			name:    "multiple items",
+			name:    "multiple legacy items",
 			content: `{"name":"John","password":"other value"}|{"name":"Jane","password":"another value"}`,
 			expected: []Data{
 				{Name: "John", Password: "other value"},
 				{Name: "Jane", Password: "another value"},
 			},
 		},
+		{
+			name:    "legacy items with separators in values",
+			content: `{"name":"a|b","password":"|","info":"x|{\"name\":\"y\"}"}|{"name":"Jane","password":"p|w"}`,
+			expected: []Data{
+				{Name: "a|b", Password: "|", Info: `x|{"name":"y"}`},
+				{Name: "Jane", Password: "p|w"},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual := stringToDataSlice(test.content)
-			if len(actual) != len(test.expected) {
-				t.Errorf("%s: expected %d items, got %d", test.name, len(test.expected), len(actual))
+			actual, err := decodeEntries(test.content)
+			if err != nil {
+				t.Fatal(err)
 			}
-			for i, item := range actual {
-				if item.Name != test.expected[i].Name || item.Password != test.expected[i].Password {
-					t.Errorf("expected item %d to be %+v, got %+v", i, test.expected[i], item)
-				}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, actual)
 			}
 		})
 	}
+
+	for _, content := range []string{`{"name":"John"}{"name":"Jane"}`, `{"name":"John"}|`, `not json`} {
+		if _, err := decodeEntries(content); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
 }
 
-func TestIsNameTaken(t *testing.T) {
-	tests := []struct {
-		name      string
-		content   string
-		nameInput string
-		expected  bool
-	}{
-		{
-			name:      "name taken",
-			content:   `{"name":"John","password":"other value"}|{"name":"Jane","password":"another value"}`,
-			nameInput: "John",
-			expected:  true,
-		},
-		{
-			name:      "name not taken",
-			content:   `{"name":"John","password":"other value"}|{"name":"Jane","password":"another value"}`,
-			nameInput: "Bob",
-			expected:  false,
-		},
-		{
-			name:      "empty content",
-			content:   "",
-			nameInput: "John",
-			expected:  false,
-		},
-		{
-			name:      "invalid JSON",
-			content:   `{"some":"John"}`,
-			nameInput: "John",
-			expected:  false,
-		},
+func TestRichEntryJSON(t *testing.T) {
+	// entries exported by versions with only three fields are still read
+	var old []Data
+	if err := json.Unmarshal([]byte(`[{"name":"acme","password":"1234","info":"old"}]`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(old, []Data{{Name: "acme", Password: "1234", Info: "old"}}) {
+		t.Errorf("unexpected entries %+v", old)
 	}
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			actual := isNameTaken(test.content, test.nameInput)
-			if actual != test.expected {
-				t.Errorf("expected %t, got %t", test.expected, actual)
-			}
-		})
+	content, err := json.Marshal(old[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `{"name":"acme","password":"1234","info":"old"}` {
+		t.Errorf("empty properties must be left out, got %s", content)
+	}
+
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	rich := Data{
+		Name:      "acme",
+		Password:  "1234",
+		Username:  "john",
+		URLs:      []string{"https://acme.com", "https://admin.acme.com"},
+		Notes:     "line 1\nline 2",
+		Tags:      []string{"work", "billing"},
+		Fields:    []Field{{Name: "pin", Type: FieldHidden, Value: "0000"}},
+		CreatedAt: now,
+		UpdatedAt: now.Add(time.Hour),
+	}
+	document, err := encodeEntries([]Data{rich})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeEntries(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, []Data{rich}) {
+		t.Errorf("expected %+v, got %+v", rich, decoded)
 	}
 }
 
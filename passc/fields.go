@@ -0,0 +1,110 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// FieldType tells how a custom field is validated and shown
+type FieldType string
+
+const (
+	FieldText   FieldType = "text"
+	FieldHidden FieldType = "hidden"
+	FieldURL    FieldType = "url"
+	FieldEmail  FieldType = "email"
+)
+
+var fieldError = errors.New(passcFieldErr)
+
+// Field is a custom value of an entry, like a PIN or a recovery email
+type Field struct {
+	Name  string    `json:"name"`
+	Type  FieldType `json:"type"`
+	Value string    `json:"value"`
+}
+
+// parseField reads the --field flag: [type:]name=value. The type defaults to text
+func parseField(flag string) (Field, error) {
+	key, value, ok := strings.Cut(flag, "=")
+	if !ok {
+		return Field{}, fmt.Errorf("%w: %s", fieldError, flag)
+	}
+
+	field := Field{Name: key, Type: FieldText, Value: value}
+	if fieldType, name, ok := strings.Cut(key, ":"); ok {
+		field.Type = FieldType(fieldType)
+		field.Name = name
+	}
+	field.Name = strings.TrimSpace(field.Name)
+
+	if field.Name == "" {
+		return Field{}, fmt.Errorf("%w: %s", fieldError, flag)
+	}
+	if field.Value == "" {
+		// an empty value removes the field
+		return field, nil
+	}
+	return field, field.validate()
+}
+
+func (f Field) validate() error {
+	switch f.Type {
+	case FieldText, FieldHidden:
+		return nil
+	case FieldURL:
+		return validateURL(f.Value)
+	case FieldEmail:
+		if _, err := mail.ParseAddress(f.Value); err != nil {
+			return fmt.Errorf("%w: invalid email %q", fieldError, f.Value)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown type %q (text, hidden, url or email)", fieldError, f.Type)
+	}
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%w: invalid URL %q", fieldError, value)
+	}
+	return nil
+}
+
+// display hides the value of hidden fields
+func (f Field) display() string {
+	if f.Type == FieldHidden {
+		return strings.Repeat("*", 8)
+	}
+	return f.Value
+}
+
+// setField replaces the field with the same name, adds it, or removes it when the value is empty
+func (d *Data) setField(field Field) {
+	for i, current := range d.Fields {
+		if current.Name == field.Name {
+			if field.Value == "" {
+				d.Fields = append(d.Fields[:i], d.Fields[i+1:]...)
+			} else {
+				d.Fields[i] = field
+			}
+			return
+		}
+	}
+	if field.Value != "" {
+		d.Fields = append(d.Fields, field)
+	}
+}
+
+func (d Data) field(name string) (Field, bool) {
+	for _, field := range d.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
@@ -0,0 +1,62 @@
+package passc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		flag     string
+		expected Field
+		err      bool
+	}{
+		{flag: "pin=1234", expected: Field{Name: "pin", Type: FieldText, Value: "1234"}},
+		{flag: "hidden:pin=12=34", expected: Field{Name: "pin", Type: FieldHidden, Value: "12=34"}},
+		{flag: "url:admin=https://acme.com/admin", expected: Field{Name: "admin", Type: FieldURL, Value: "https://acme.com/admin"}},
+		{flag: "email:recovery=john@acme.com", expected: Field{Name: "recovery", Type: FieldEmail, Value: "john@acme.com"}},
+		{flag: "email:recovery=", expected: Field{Name: "recovery", Type: FieldEmail}},
+		{flag: "url:admin=acme.com", err: true},
+		{flag: "email:recovery=john", err: true},
+		{flag: "date:expires=2024", err: true},
+		{flag: "pin", err: true},
+		{flag: "=1234", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.flag, func(t *testing.T) {
+			field, err := parseField(test.flag)
+			if test.err {
+				if !errors.Is(err, fieldError) {
+					t.Errorf("expected a field error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if field != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, field)
+			}
+		})
+	}
+}
+
+func TestSetField(t *testing.T) {
+	data := Data{Name: "acme"}
+	data.setField(Field{Name: "pin", Type: FieldHidden, Value: "1234"})
+	data.setField(Field{Name: "recovery", Type: FieldEmail, Value: "john@acme.com"})
+	data.setField(Field{Name: "pin", Type: FieldHidden, Value: "4321"})
+
+	if len(data.Fields) != 2 {
+		t.Fatalf("expected 2 fields, got %+v", data.Fields)
+	}
+	if field, ok := data.field("pin"); !ok || field.Value != "4321" || field.display() == "4321" {
+		t.Errorf("expected a replaced and hidden pin, got %+v %v", field, ok)
+	}
+
+	data.setField(Field{Name: "pin"})
+	if _, ok := data.field("pin"); ok || len(data.Fields) != 1 {
+		t.Errorf("an empty value must remove the field, got %+v", data.Fields)
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
-	"strings"
 
 	"crypto/aes"
 	"crypto/cipher"
@@ -19,48 +17,30 @@ import (
 type Encryptor struct {
 	MasterPassword string
 	FilePath       string
+	KDF            KDFParams
+	key            *storeKey
 }
 
 var emptyFile = errors.New(passcEmptyFile)
 
 func (e Encryptor) deleteContent() error {
-	file, err := os.OpenFile(e.FilePath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	return nil
+	return writeFileAtomic(e.FilePath, nil, 0644)
 }
 
-func (e Encryptor) encryptText(text string, isAppend bool) error {
-	flag := os.O_CREATE | os.O_WRONLY
-	if !isAppend {
-		flag = os.O_WRONLY | os.O_TRUNC | os.O_CREATE
-	}
-	file, err := os.OpenFile(e.FilePath, flag, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	finalText := text
-	stat, _ := file.Stat()
-	if stat.Size() != 0 {
-		if isAppend {
-			decryptedText, err := e.readEncryptedText()
-			if err != nil {
-				return err
-			}
-			finalText += passcItemSeparator + decryptedText
+func (e Encryptor) encryptText(text string) error {
+	key := e.key
+	if key == nil {
+		if e.MasterPassword == "" {
+			return invalidPasswordError
 		}
+		salt, err := newSalt()
+		if err != nil {
+			return err
+		}
+		key = e.deriveStoreKey(salt, e.kdfParams())
 	}
 
-	block, err := aes.NewCipher([]byte(e.MasterPassword))
-	if err != nil {
-		return err
-	}
-
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(key.Key)
 	if err != nil {
 		return err
 	}
@@ -70,114 +50,137 @@ func (e Encryptor) encryptText(text string, isAppend bool) error {
 		return err
 	}
 
-	ciphertext := gcm.Seal(nil, nonce, []byte(finalText), nil)
-
-	if _, err := file.Write(append(nonce, ciphertext...)); err != nil {
-		return err
-	}
+	header := newStoreHeader(key.KDF, key.Salt, key.Key, nonce).marshal()
+	ciphertext := gcm.Seal(nil, nonce, []byte(text), header)
 
-	return nil
+	return writeFileAtomic(e.FilePath, append(header, ciphertext...), 0644)
 }
 
 func (e Encryptor) readEncryptedText() (string, error) {
-	file, err := os.Open(e.FilePath)
+	data, err := os.ReadFile(e.FilePath)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return "", emptyFile
+		}
 		return "", fmt.Errorf("open file %s: %v", e.FilePath, err)
 	}
-	defer file.Close()
 
-	block, err := aes.NewCipher([]byte(e.MasterPassword))
+	if len(data) == 0 {
+		return "", emptyFile
+	}
+
+	header, additionalData, ciphertext, err := parseStore(data)
 	if err != nil {
-		return "", fmt.Errorf("cipher: %v", err)
+		return "", err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	key, err := e.keyFor(header)
 	if err != nil {
-		return "", fmt.Errorf("gcm: %v", err)
+		return "", err
 	}
 
-	data, err := io.ReadAll(file)
+	gcm, err := newGCM(key.Key)
 	if err != nil {
-		return "", fmt.Errorf("reading file: %v", err)
+		return "", err
 	}
 
-	nonce := data[:gcm.NonceSize()]
-	if len(data) == 0 {
-		return "", emptyFile
+	if len(header.Nonce) != gcm.NonceSize() {
+		return "", tamperedFileError
 	}
-	ciphertext := data[gcm.NonceSize():]
 
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, header.Nonce, ciphertext, additionalData)
 	if err != nil {
-		return "", fmt.Errorf("extracting plaintext: %v", err)
+		return "", tamperedFileError
 	}
 
 	return string(plaintext), nil
 }
 
-func exportToFile(content string) error {
-	items := strings.Split(content, passcItemSeparator)
-	file, err := os.OpenFile(passcExportFilename, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
-	if err != nil {
-		return err
+// unlock derives the store key once so every following read and write can reuse it.
+// An empty or missing store keeps the key it was given, the one of the agent, or gets a fresh salt.
+// A key is never derived without a master password
+func (e *Encryptor) unlock() (*storeKey, error) {
+	data, err := os.ReadFile(e.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("open file %s: %v", e.FilePath, err)
 	}
-	defer file.Close()
 
-	_, err = file.WriteString("[")
+	if len(data) == 0 {
+		if e.key != nil {
+			return e.key, nil
+		}
+		if e.MasterPassword == "" {
+			return nil, invalidPasswordError
+		}
+		salt, err := newSalt()
+		if err != nil {
+			return nil, err
+		}
+		e.key = e.deriveStoreKey(salt, e.kdfParams())
+		return e.key, nil
+	}
+
+	header, _, _, err := parseStore(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	length := len(items)
-	for i, v := range items {
-		var prettyJSON bytes.Buffer
-		err := json.Indent(&prettyJSON, []byte(v), "", "  ")
-		if err != nil {
-			return err
-		}
+	key, err := e.keyFor(header)
+	if err != nil {
+		return nil, err
+	}
+	e.key = key
+	return key, nil
+}
 
-		coma := ","
-		if i == length-1 {
-			coma = ""
+// keyFor returns the unlocked key when it matches the header, otherwise derives it from the master password
+func (e Encryptor) keyFor(header *storeHeader) (*storeKey, error) {
+	key := e.key
+	if key == nil || !bytes.Equal(key.Salt, header.Salt) || key.KDF != header.KDF {
+		if e.MasterPassword == "" {
+			return nil, invalidPasswordError
 		}
+		key = e.deriveStoreKey(header.Salt, header.KDF)
+	}
 
-		_, err = file.WriteString(prettyJSON.String() + coma)
-		if err != nil {
-			return err
-		}
+	if err := header.verifyKey(key.Key); err != nil {
+		return nil, err
 	}
-	_, err = file.WriteString("]")
-	if err != nil {
-		return err
+	return key, nil
+}
+
+func (e Encryptor) deriveStoreKey(salt []byte, params KDFParams) *storeKey {
+	return &storeKey{
+		Key:  deriveKey(e.MasterPassword, salt, params),
+		Salt: salt,
+		KDF:  params,
 	}
-	return nil
 }
 
-func makeBackUp() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Printf("finding user home dir: %v\n", err)
-		return
+func (e Encryptor) kdfParams() KDFParams {
+	if e.KDF == (KDFParams{}) {
+		return defaultKDFParams()
 	}
-	src := homeDir + "/" + passcPathStoreFile
-	srcFile, err := os.Open(src)
+	return e.KDF
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		log.Printf("opening file: %v\n", err)
-		return
+		return nil, fmt.Errorf("cipher: %v", err)
 	}
-	defer srcFile.Close()
 
-	dst := homeDir + "/" + passcDirFolder + "/" + passcBackUpFile
-	dstFile, err := os.Create(dst)
+	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		log.Printf("creating file: %v\n", err)
-		return
+		return nil, fmt.Errorf("gcm: %v", err)
 	}
-	defer dstFile.Close()
+	return gcm, nil
+}
 
-	_, err = io.Copy(dstFile, srcFile)
+func exportToFile(items []Data) error {
+	content, err := json.MarshalIndent(items, "", "  ")
 	if err != nil {
-		log.Printf("copying to backup file: %v\n", err)
-		return
+		return err
 	}
+	return os.WriteFile(passcExportFilename, content, 0644)
 }
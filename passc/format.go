@@ -0,0 +1,151 @@
+package passc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Store file layout (all integers big endian):
+//
+//	magic     [4]byte  "PSCL"
+//	version   uint8
+//	cipher    uint8
+//	kdf       uint8
+//	time      uint32
+//	memory    uint32
+//	threads   uint8
+//	saltLen   uint8
+//	salt      [saltLen]byte
+//	keyCheck  [16]byte
+//	nonceLen  uint8
+//	nonce     [nonceLen]byte
+//	ciphertext
+//
+// Everything before the ciphertext is authenticated as AEAD additional data.
+const (
+	formatVersion     uint8 = 1
+	cipherAES256GCM   uint8 = 1
+	kdfArgon2id       uint8 = 1
+	keyCheckSize            = 16
+	headerFixedSize         = 4 + 1 + 1 + 1 + 4 + 4 + 1 + 1
+	keyCheckDomainStr       = "passcualito key check"
+)
+
+var formatMagic = []byte("PSCL")
+
+var (
+	unknownFormatError      = errors.New(passcUnknownFormatErr)
+	unsupportedVersionError = errors.New(passcUnsupportedVersionErr)
+	truncatedFileError      = errors.New(passcTruncatedErr)
+	tamperedFileError       = errors.New(passcTamperedErr)
+	invalidPasswordError    = errors.New(passcInvalidPassword)
+)
+
+type storeHeader struct {
+	Version  uint8
+	Cipher   uint8
+	KDFId    uint8
+	KDF      KDFParams
+	Salt     []byte
+	KeyCheck []byte
+	Nonce    []byte
+}
+
+func newStoreHeader(params KDFParams, salt, key, nonce []byte) storeHeader {
+	return storeHeader{
+		Version:  formatVersion,
+		Cipher:   cipherAES256GCM,
+		KDFId:    kdfArgon2id,
+		KDF:      params,
+		Salt:     salt,
+		KeyCheck: keyCheck(key),
+		Nonce:    nonce,
+	}
+}
+
+func (h storeHeader) marshal() []byte {
+	buf := append([]byte{}, formatMagic...)
+	buf = append(buf, h.Version, h.Cipher, h.KDFId)
+	buf = binary.BigEndian.AppendUint32(buf, h.KDF.Time)
+	buf = binary.BigEndian.AppendUint32(buf, h.KDF.Memory)
+	buf = append(buf, h.KDF.Threads, uint8(len(h.Salt)))
+	buf = append(buf, h.Salt...)
+	buf = append(buf, h.KeyCheck...)
+	buf = append(buf, uint8(len(h.Nonce)))
+	buf = append(buf, h.Nonce...)
+	return buf
+}
+
+// parseStore splits a store file into its header, the raw header bytes (AEAD additional data) and the ciphertext
+func parseStore(data []byte) (*storeHeader, []byte, []byte, error) {
+	if len(data) < len(formatMagic) || !bytes.Equal(data[:len(formatMagic)], formatMagic) {
+		return nil, nil, nil, unknownFormatError
+	}
+	if len(data) < headerFixedSize {
+		return nil, nil, nil, truncatedFileError
+	}
+
+	header := storeHeader{
+		Version: data[4],
+		Cipher:  data[5],
+		KDFId:   data[6],
+		KDF: KDFParams{
+			Time:    binary.BigEndian.Uint32(data[7:11]),
+			Memory:  binary.BigEndian.Uint32(data[11:15]),
+			Threads: data[15],
+		},
+	}
+
+	if header.Version != formatVersion {
+		return nil, nil, nil, fmt.Errorf("%w: %d", unsupportedVersionError, header.Version)
+	}
+	if header.Cipher != cipherAES256GCM {
+		return nil, nil, nil, fmt.Errorf("%w: unknown cipher %d", unsupportedVersionError, header.Cipher)
+	}
+	if header.KDFId != kdfArgon2id {
+		return nil, nil, nil, fmt.Errorf("%w: unknown kdf %d", unsupportedVersionError, header.KDFId)
+	}
+	if err := header.KDF.validate(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", tamperedFileError, err)
+	}
+
+	offset := headerFixedSize
+	saltLen := int(data[offset-1])
+	if saltLen == 0 {
+		return nil, nil, nil, tamperedFileError
+	}
+	if len(data) < offset+saltLen+keyCheckSize+1 {
+		return nil, nil, nil, truncatedFileError
+	}
+	header.Salt = data[offset : offset+saltLen]
+	offset += saltLen
+	header.KeyCheck = data[offset : offset+keyCheckSize]
+	offset += keyCheckSize
+
+	nonceLen := int(data[offset])
+	offset++
+	if len(data) < offset+nonceLen {
+		return nil, nil, nil, truncatedFileError
+	}
+	header.Nonce = data[offset : offset+nonceLen]
+	offset += nonceLen
+
+	return &header, data[:offset], data[offset:], nil
+}
+
+// keyCheck lets a reader tell a wrong master password apart from a damaged file
+func keyCheck(key []byte) []byte {
+	sum := sha256.Sum256(append([]byte(keyCheckDomainStr), key...))
+	return sum[:keyCheckSize]
+}
+
+func (h storeHeader) verifyKey(key []byte) error {
+	if subtle.ConstantTimeCompare(h.KeyCheck, keyCheck(key)) != 1 {
+		return invalidPasswordError
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package passc
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadEncryptedTextErrors(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	encryptor := Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}
+	if err := encryptor.encryptText(`{"name":"john","password":"1234","info":""}`); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, additionalData, _, err := parseStore(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonceOffset := len(additionalData) - len(header.Nonce)
+
+	tests := []struct {
+		name     string
+		password string
+		content  func([]byte) []byte
+		expected error
+	}{
+		{
+			name:     "empty file",
+			password: "p4$$w0rd",
+			content:  func(b []byte) []byte { return nil },
+			expected: emptyFile,
+		},
+		{
+			name:     "wrong password",
+			password: "wr0ng",
+			content:  func(b []byte) []byte { return b },
+			expected: invalidPasswordError,
+		},
+		{
+			name:     "legacy layout",
+			password: "p4$$w0rd",
+			content:  func(b []byte) []byte { return b[len(formatMagic):] },
+			expected: unknownFormatError,
+		},
+		{
+			name:     "unknown version",
+			password: "p4$$w0rd",
+			content: func(b []byte) []byte {
+				b[len(formatMagic)] = formatVersion + 1
+				return b
+			},
+			expected: unsupportedVersionError,
+		},
+		{
+			name:     "truncated header",
+			password: "p4$$w0rd",
+			content:  func(b []byte) []byte { return b[:headerFixedSize+4] },
+			expected: truncatedFileError,
+		},
+		{
+			name:     "only magic",
+			password: "p4$$w0rd",
+			content:  func(b []byte) []byte { return b[:len(formatMagic)] },
+			expected: truncatedFileError,
+		},
+		{
+			name:     "oversized kdf memory",
+			password: "p4$$w0rd",
+			content: func(b []byte) []byte {
+				binary.BigEndian.PutUint32(b[11:15], kdfMaxMemory+1)
+				return b
+			},
+			expected: tamperedFileError,
+		},
+		{
+			name:     "oversized kdf time",
+			password: "p4$$w0rd",
+			content: func(b []byte) []byte {
+				binary.BigEndian.PutUint32(b[7:11], kdfMaxTime+1)
+				return b
+			},
+			expected: tamperedFileError,
+		},
+		{
+			name:     "oversized kdf threads",
+			password: "p4$$w0rd",
+			content: func(b []byte) []byte {
+				binary.BigEndian.PutUint32(b[11:15], kdfMaxMemory)
+				b[15] = kdfMaxThreads + 1
+				return b
+			},
+			expected: tamperedFileError,
+		},
+		{
+			name:     "tampered nonce",
+			password: "p4$$w0rd",
+			content: func(b []byte) []byte {
+				b[nonceOffset] ^= 0xff
+				return b
+			},
+			expected: tamperedFileError,
+		},
+		{
+			name:     "tampered ciphertext",
+			password: "p4$$w0rd",
+			content: func(b []byte) []byte {
+				b[len(b)-1] ^= 0xff
+				return b
+			},
+			expected: tamperedFileError,
+		},
+		{
+			name:     "truncated ciphertext",
+			password: "p4$$w0rd",
+			content:  func(b []byte) []byte { return b[:len(b)-4] },
+			expected: tamperedFileError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := test.content(append([]byte{}, original...))
+			if err := os.WriteFile(filePath, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			reader := Encryptor{MasterPassword: test.password, FilePath: filePath}
+			_, err := reader.readEncryptedText()
+			if !errors.Is(err, test.expected) {
+				t.Errorf("expected error %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestStoreHeaderRoundTrip(t *testing.T) {
+	key := deriveKey("p4$$w0rd", make([]byte, kdfSaltSize), testKDFParams)
+	header := newStoreHeader(testKDFParams, make([]byte, kdfSaltSize), key, make([]byte, 12))
+
+	raw := append(header.marshal(), []byte("ciphertext")...)
+	parsed, additionalData, ciphertext, err := parseStore(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.KDF != testKDFParams || len(parsed.Salt) != kdfSaltSize || len(parsed.Nonce) != 12 {
+		t.Errorf("unexpected header %+v", parsed)
+	}
+	if string(ciphertext) != "ciphertext" || len(additionalData) != len(header.marshal()) {
+		t.Errorf("unexpected split: %q", ciphertext)
+	}
+	if err := parsed.verifyKey(key); err != nil {
+		t.Errorf("expected key to verify, got %v", err)
+	}
+}
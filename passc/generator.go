@@ -0,0 +1,271 @@
+package passc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// randomSource feeds the generator. Tests swap it to check the rejection sampling
+var randomSource io.Reader = rand.Reader
+
+var policyError = errors.New(passcPolicyErr)
+
+// Policy tells how passwords are generated. Each enabled class may require a minimum of characters,
+// the rest are drawn from every enabled class together
+type Policy struct {
+	Length  int  `json:"length"`
+	Lower   bool `json:"lower"`
+	Upper   bool `json:"upper"`
+	Digits  bool `json:"digits"`
+	Symbols bool `json:"symbols"`
+
+	MinLower   int `json:"minLower,omitempty"`
+	MinUpper   int `json:"minUpper,omitempty"`
+	MinDigits  int `json:"minDigits,omitempty"`
+	MinSymbols int `json:"minSymbols,omitempty"`
+
+	// SymbolSet replaces the default symbols
+	SymbolSet string `json:"symbolSet,omitempty"`
+	// Exclude leaves out any character the site does not accept
+	Exclude string `json:"exclude,omitempty"`
+	// ExcludeAmbiguous leaves out characters easy to confuse like 0O1lI
+	ExcludeAmbiguous bool `json:"excludeAmbiguous,omitempty"`
+	// NoRepeats uses each character at most once
+	NoRepeats bool `json:"noRepeats,omitempty"`
+}
+
+func defaultPolicy() Policy {
+	return Policy{Length: 20, Lower: true, Upper: true, Digits: true, Symbols: true}
+}
+
+// Profile is a named generator preset of config.json, for sites with their own rules. The keys left
+// out keep the default policy. With passphrase it generates words instead of characters
+type Profile struct {
+	Policy
+	Passphrase *PassphrasePolicy `json:"passphrase,omitempty"`
+}
+
+func defaultProfile() Profile {
+	return Profile{Policy: defaultPolicy()}
+}
+
+func (p *Profile) UnmarshalJSON(b []byte) error {
+	type profile Profile
+	value := profile(defaultProfile())
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	*p = Profile(value)
+	return nil
+}
+
+func (p Profile) validate() error {
+	if p.Passphrase != nil {
+		return p.Passphrase.validate()
+	}
+	return p.Policy.validate()
+}
+
+func (p Profile) Entropy() float64 {
+	if p.Passphrase != nil {
+		return p.Passphrase.Entropy()
+	}
+	return p.Policy.Entropy()
+}
+
+// withLength changes the characters of a password or the words of a passphrase
+func (p Profile) withLength(length int) Profile {
+	if p.Passphrase != nil {
+		passphrase := *p.Passphrase
+		passphrase.Words = length
+		p.Passphrase = &passphrase
+		return p
+	}
+	p.Length = length
+	return p
+}
+
+// generate makes a password or a passphrase following the profile
+func (p Profile) generate() (string, error) {
+	if p.Passphrase != nil {
+		return generatePassphrase(*p.Passphrase)
+	}
+	return generatePassword(p.Policy)
+}
+
+type charClass struct {
+	name    string
+	enabled bool
+	min     int
+	chars   []rune
+}
+
+// classes returns the characters of each class after removing ambiguous and repeated ones
+func (p Policy) classes() []charClass {
+	symbols := passcCharsetSymbols
+	if p.SymbolSet != "" {
+		symbols = p.SymbolSet
+	}
+
+	seen := make(map[rune]bool)
+	charset := func(chars string) []rune {
+		var runes []rune
+		for _, r := range chars {
+			if seen[r] || strings.ContainsRune(p.Exclude, r) || (p.ExcludeAmbiguous && strings.ContainsRune(passcCharsetAmbiguous, r)) {
+				continue
+			}
+			seen[r] = true
+			runes = append(runes, r)
+		}
+		return runes
+	}
+
+	return []charClass{
+		{name: "lower", enabled: p.Lower, min: p.MinLower, chars: charset(passcCharsetAlpha)},
+		{name: "upper", enabled: p.Upper, min: p.MinUpper, chars: charset(passcCharsetUpper)},
+		{name: "digits", enabled: p.Digits, min: p.MinDigits, chars: charset(passcCharsetNumeric)},
+		{name: "symbols", enabled: p.Symbols, min: p.MinSymbols, chars: charset(symbols)},
+	}
+}
+
+// charset is every character the policy can use
+func (p Policy) charset() []rune {
+	var chars []rune
+	for _, class := range p.classes() {
+		if class.enabled {
+			chars = append(chars, class.chars...)
+		}
+	}
+	return chars
+}
+
+func (p Policy) validate() error {
+	if p.Length < 1 {
+		return fmt.Errorf("%w: length must be positive", policyError)
+	}
+
+	required := 0
+	for _, class := range p.classes() {
+		if class.min < 0 {
+			return fmt.Errorf("%w: minimum %s must not be negative", policyError, class.name)
+		}
+		if class.min > 0 && !class.enabled {
+			return fmt.Errorf("%w: minimum %s needs %s characters enabled", policyError, class.name, class.name)
+		}
+		if class.enabled && len(class.chars) == 0 {
+			return fmt.Errorf("%w: no %s characters left", policyError, class.name)
+		}
+		if p.NoRepeats && class.min > len(class.chars) {
+			return fmt.Errorf("%w: %d %s characters without repeats", policyError, class.min, class.name)
+		}
+		required += class.min
+	}
+
+	charset := p.charset()
+	switch {
+	case len(charset) == 0:
+		return fmt.Errorf("%w: no character class enabled", policyError)
+	case required > p.Length:
+		return fmt.Errorf("%w: the minimums need %d characters, length is %d", policyError, required, p.Length)
+	case p.NoRepeats && p.Length > len(charset):
+		return fmt.Errorf("%w: %d characters without repeats from %d", policyError, p.Length, len(charset))
+	}
+	return nil
+}
+
+// Entropy is the bits of a password drawn uniformly from the charset. Minimums lower it a little
+func (p Policy) Entropy() float64 {
+	size := float64(len(p.charset()))
+	if !p.NoRepeats {
+		return float64(p.Length) * math.Log2(size)
+	}
+	bits := 0.0
+	for i := range p.Length {
+		bits += math.Log2(size - float64(i))
+	}
+	return bits
+}
+
+// generatePassword picks the minimum characters of each class first, fills the rest from the whole
+// charset and shuffles the result. Every pick is uniform thanks to randomIndex
+func generatePassword(p Policy) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	used := make(map[rune]bool)
+	pick := func(chars []rune) (rune, error) {
+		if p.NoRepeats {
+			chars = removeRunes(chars, used)
+		}
+		i, err := randomIndex(len(chars))
+		if err != nil {
+			return 0, err
+		}
+		used[chars[i]] = true
+		return chars[i], nil
+	}
+
+	password := make([]rune, 0, p.Length)
+	for _, class := range p.classes() {
+		for range class.min {
+			r, err := pick(class.chars)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, r)
+		}
+	}
+	charset := p.charset()
+	for len(password) < p.Length {
+		r, err := pick(charset)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, r)
+	}
+
+	// Fisher-Yates, so the required characters can be anywhere
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// randomIndex returns a uniform number in [0, n). Random values above the largest multiple of n are
+// rejected, otherwise value % n would favor the lowest numbers
+func randomIndex(n int) (int, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("%w: nothing to pick from", policyError)
+	}
+	limit := math.MaxUint32 - (math.MaxUint32%uint64(n)+1)%uint64(n)
+	var buf [4]byte
+	for {
+		if _, err := io.ReadFull(randomSource, buf[:]); err != nil {
+			return 0, err
+		}
+		if value := uint64(binary.BigEndian.Uint32(buf[:])); value <= limit {
+			return int(value % uint64(n)), nil
+		}
+	}
+}
+
+func removeRunes(chars []rune, used map[rune]bool) []rune {
+	left := make([]rune, 0, len(chars))
+	for _, r := range chars {
+		if !used[r] {
+			left = append(left, r)
+		}
+	}
+	return left
+}
@@ -0,0 +1,226 @@
+package passc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	mathrand "math/rand/v2"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		check  func(string) bool
+	}{
+		{
+			name:   "default",
+			policy: defaultPolicy(),
+			check:  func(p string) bool { return len(p) == 20 },
+		},
+		{
+			name:   "digits only",
+			policy: Policy{Length: 12, Digits: true},
+			check:  func(p string) bool { return len(p) == 12 && isDigits(p) },
+		},
+		{
+			name:   "minimum per class",
+			policy: Policy{Length: 8, Lower: true, Upper: true, Digits: true, Symbols: true, MinUpper: 3, MinDigits: 2, MinSymbols: 1},
+			check: func(p string) bool {
+				return countRunes(p, unicode.IsUpper) >= 3 && countRunes(p, unicode.IsDigit) >= 2 && strings.ContainsAny(p, passcCharsetSymbols)
+			},
+		},
+		{
+			name:   "custom symbols",
+			policy: Policy{Length: 30, Symbols: true, SymbolSet: "-_"},
+			check:  func(p string) bool { return strings.Trim(p, "-_") == "" },
+		},
+		{
+			name:   "no ambiguous",
+			policy: Policy{Length: 200, Lower: true, Upper: true, Digits: true, ExcludeAmbiguous: true},
+			check:  func(p string) bool { return !strings.ContainsAny(p, passcCharsetAmbiguous) },
+		},
+		{
+			name:   "no repeats",
+			policy: Policy{Length: 10, Digits: true, NoRepeats: true},
+			check: func(p string) bool {
+				for _, digit := range passcCharsetNumeric {
+					if strings.Count(p, string(digit)) != 1 {
+						return false
+					}
+				}
+				return true
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for range 100 {
+				password, err := generatePassword(test.policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !test.check(password) {
+					t.Fatalf("unexpected password %q", password)
+				}
+			}
+		})
+	}
+}
+
+func countRunes(value string, is func(rune) bool) int {
+	count := 0
+	for _, r := range value {
+		if is(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	for _, policy := range []Policy{
+		{Length: 0, Lower: true},
+		{Length: 8},
+		{Length: 8, Lower: true, MinLower: -1},
+		{Length: 8, Lower: true, MinDigits: 1},
+		{Length: 3, Lower: true, Digits: true, MinLower: 2, MinDigits: 2},
+		{Length: 11, Digits: true, NoRepeats: true},
+		{Length: 8, Symbols: true, SymbolSet: "0O", ExcludeAmbiguous: true},
+	} {
+		if _, err := generatePassword(policy); !errors.Is(err, policyError) {
+			t.Errorf("%+v: expected policy error, got %v", policy, err)
+		}
+	}
+}
+
+func TestPolicyCharset(t *testing.T) {
+	// the default charset has no repeated characters
+	charset := defaultPolicy().charset()
+	if len(charset) != 26+26+10+len(passcCharsetSymbols) {
+		t.Errorf("unexpected charset %q", string(charset))
+	}
+	// symbols already used by another class are not counted twice
+	if charset := (Policy{Digits: true, Symbols: true, SymbolSet: "123-"}).charset(); string(charset) != passcCharsetNumeric+"-" {
+		t.Errorf("unexpected charset %q", string(charset))
+	}
+	if charset := (Policy{Digits: true, Exclude: "13579"}).charset(); string(charset) != "02468" {
+		t.Errorf("unexpected charset %q", string(charset))
+	}
+	if err := (Policy{Length: 4, Digits: true, Exclude: passcCharsetNumeric}).validate(); !errors.Is(err, policyError) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	var profiles map[string]Profile
+	content := `{"bank": {"length": 16, "symbols": false}, "master": {"passphrase": {"words": 7}}}`
+	if err := json.Unmarshal([]byte(content), &profiles); err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys left out keep their default
+	bank := defaultPolicy()
+	bank.Length, bank.Symbols = 16, false
+	if profiles["bank"].Policy != bank || profiles["bank"].Passphrase != nil {
+		t.Errorf("unexpected profile %+v", profiles["bank"])
+	}
+	master := profiles["master"]
+	if master.Passphrase == nil || *master.Passphrase != (PassphrasePolicy{Words: 7, Separator: "-"}) {
+		t.Errorf("unexpected profile %+v", master)
+	}
+
+	password, err := profiles["bank"].withLength(10).generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) != 10 || strings.ContainsAny(password, passcCharsetSymbols) {
+		t.Errorf("unexpected password %q", password)
+	}
+
+	// withLength does not change the passphrase of the config
+	if words := master.withLength(3).Passphrase.Words; words != 3 || master.Passphrase.Words != 7 {
+		t.Errorf("unexpected words %d and %d", words, master.Passphrase.Words)
+	}
+	if bits := master.Entropy(); math.Abs(bits-master.Passphrase.Entropy()) > 1e-9 {
+		t.Errorf("unexpected entropy %v", bits)
+	}
+}
+
+func TestPolicyEntropy(t *testing.T) {
+	if bits := (Policy{Length: 10, Digits: true}).Entropy(); math.Abs(bits-10*math.Log2(10)) > 1e-9 {
+		t.Errorf("unexpected entropy %v", bits)
+	}
+	if bits := (Policy{Length: 10, Digits: true, NoRepeats: true}).Entropy(); math.Abs(bits-math.Log2(3628800)) > 1e-9 {
+		t.Errorf("unexpected entropy %v", bits)
+	}
+}
+
+func TestRandomIndexRejection(t *testing.T) {
+	// 2^32 is 1 modulo 3: the largest value would favor 0 and must be skipped
+	var source bytes.Buffer
+	for _, value := range []uint32{math.MaxUint32, math.MaxUint32 - 1, 7} {
+		binary.Write(&source, binary.BigEndian, value)
+	}
+	randomSource = &source
+	t.Cleanup(func() { randomSource = rand.Reader })
+
+	for _, expected := range []int{int((math.MaxUint32 - 1) % 3), 7 % 3} {
+		index, err := randomIndex(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index != expected {
+			t.Errorf("expected %d, got %d", expected, index)
+		}
+	}
+}
+
+// TestGeneratorUniformity runs a chi-squared test on the characters of generated passwords against
+// the 0.1% critical values. The random stream is seeded so every run draws the same passwords
+func TestGeneratorUniformity(t *testing.T) {
+	randomSource = mathrand.NewChaCha8([32]byte([]byte("passcualito generator uniformity")))
+	t.Cleanup(func() { randomSource = rand.Reader })
+
+	tests := []struct {
+		policy   Policy
+		critical float64
+	}{
+		{Policy{Length: 50, Digits: true}, 27.88},                                          // 9 degrees of freedom
+		{Policy{Length: 50, Lower: true, Upper: true, Digits: true}, 100.89},               // 61
+		{Policy{Length: 50, Lower: true, Upper: true, Digits: true, Symbols: true}, 113.6}, // 71
+	}
+
+	for _, test := range tests {
+		charset := test.policy.charset()
+		counts := make(map[rune]int)
+		total := 0
+		for range 2000 {
+			password, err := generatePassword(test.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range password {
+				counts[r]++
+				total++
+			}
+		}
+
+		expected := float64(total) / float64(len(charset))
+		chiSquared := 0.0
+		for _, r := range charset {
+			diff := float64(counts[r]) - expected
+			chiSquared += diff * diff / expected
+		}
+		if chiSquared > test.critical {
+			t.Errorf("%d characters: chi-squared %.2f above %.2f", len(charset), chiSquared, test.critical)
+		}
+	}
+}
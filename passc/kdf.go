@@ -0,0 +1,63 @@
+package passc
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	kdfSaltSize = 16
+	kdfKeySize  = 32
+
+	// The parameters come from the unauthenticated store header: the upper limits, a few times the
+	// defaults, keep a forged one from exhausting memory or hanging passc before the key check
+	kdfMaxTime    = 16
+	kdfMaxMemory  = 1024 * 1024
+	kdfMaxThreads = 16
+)
+
+// KDFParams are the Argon2id cost parameters. Memory is expressed in KiB.
+type KDFParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+func defaultKDFParams() KDFParams {
+	return KDFParams{
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+}
+
+func (p KDFParams) validate() error {
+	if p.Time < 1 || p.Time > kdfMaxTime {
+		return fmt.Errorf("kdf time must be between 1 and %d", kdfMaxTime)
+	}
+	if p.Threads < 1 || p.Threads > kdfMaxThreads {
+		return fmt.Errorf("kdf threads must be between 1 and %d", kdfMaxThreads)
+	}
+	if p.Memory < 8*uint32(p.Threads) {
+		return fmt.Errorf("kdf memory must be at least %d KiB", 8*uint32(p.Threads))
+	}
+	if p.Memory > kdfMaxMemory {
+		return fmt.Errorf("kdf memory must be at most %d KiB", kdfMaxMemory)
+	}
+	return nil
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// deriveKey stretches the whole master password into a 256-bit AES key
+func deriveKey(password string, salt []byte, params KDFParams) []byte {
+	return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, kdfKeySize)
+}
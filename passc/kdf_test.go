@@ -0,0 +1,91 @@
+package passc
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+var testKDFParams = KDFParams{Time: 1, Memory: 64, Threads: 1}
+
+func TestDeriveKeyUsesWholePassword(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, kdfSaltSize)
+	password1 := "1234567890abcdef-first"
+	password2 := "1234567890abcdef-second"
+
+	if alignPassword(password1) != alignPassword(password2) {
+		t.Fatal("legacy keys were expected to collide")
+	}
+
+	key1 := deriveKey(password1, salt, testKDFParams)
+	key2 := deriveKey(password2, salt, testKDFParams)
+	if len(key1) != 32 {
+		t.Errorf("expected a 256-bit key, got %d bytes", len(key1))
+	}
+	if bytes.Equal(key1, key2) {
+		t.Error("passwords sharing the first 16 chars must derive different keys")
+	}
+}
+
+func TestDeriveKeySalt(t *testing.T) {
+	salt1, err := newSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt2, err := newSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key1 := deriveKey("p4$$w0rd", salt1, testKDFParams)
+	key2 := deriveKey("p4$$w0rd", salt2, testKDFParams)
+	if bytes.Equal(key1, key2) {
+		t.Error("same password with different salts must derive different keys")
+	}
+
+	if !bytes.Equal(key1, deriveKey("p4$$w0rd", salt1, testKDFParams)) {
+		t.Error("derivation must be deterministic for the same salt")
+	}
+}
+
+func TestEncryptorRoundTrip(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	encryptor := Encryptor{MasterPassword: "1234567890abcdef-first", FilePath: filePath, KDF: testKDFParams}
+
+	text, err := encodeEntries([]Data{{Name: "john", Password: "1234"}, {Name: "jane", Password: "5678"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := encryptor.encryptText(text); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := encryptor.readEncryptedText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != text {
+		t.Errorf("expected %q, got %q", text, content)
+	}
+
+	wrong := Encryptor{MasterPassword: "1234567890abcdef-second", FilePath: filePath}
+	if _, err := wrong.readEncryptedText(); err == nil {
+		t.Error("a password sharing the first 16 chars must not decrypt the store")
+	}
+}
+
+func TestKDFParamsBounds(t *testing.T) {
+	if err := (KDFParams{Time: kdfMaxTime, Memory: kdfMaxMemory, Threads: kdfMaxThreads}).validate(); err != nil {
+		t.Errorf("the limits themselves must be accepted, got %v", err)
+	}
+	for _, params := range []KDFParams{
+		{Time: kdfMaxTime + 1, Memory: 64 * 1024, Threads: 4},
+		{Time: 3, Memory: kdfMaxMemory + 1, Threads: 4},
+		{Time: 3, Memory: 64 * 1024, Threads: kdfMaxThreads + 1},
+		{Time: 0, Memory: 64 * 1024, Threads: 4},
+	} {
+		if err := params.validate(); err == nil {
+			t.Errorf("expected an error for %+v", params)
+		}
+	}
+}
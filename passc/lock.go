@@ -0,0 +1,98 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const storeLockRetry = 50 * time.Millisecond
+
+var (
+	// storeLockTimeout is how long a command waits for another one to finish changing the vault
+	storeLockTimeout = 10 * time.Second
+
+	vaultBusyError = errors.New(passcVaultBusyErr)
+)
+
+// Locker is implemented by vaults that several passc processes can change at the same time
+type Locker interface {
+	Lock() error
+	Unlock() error
+}
+
+// lockVault holds the vault lock until the returned func is called, so a read
+// followed by a write cannot lose the changes of another command in between
+func lockVault(vault Vault) (func(), error) {
+	locker, ok := vault.(Locker)
+	if !ok {
+		return func() {}, nil
+	}
+	if err := locker.Lock(); err != nil {
+		return nil, err
+	}
+	return func() { locker.Unlock() }, nil
+}
+
+// storeLock is an exclusive flock on a file next to the store. It is reentrant
+// so vault methods can take it while a command already holds it
+type storeLock struct {
+	path  string
+	file  *os.File
+	depth int
+}
+
+func newStoreLock(filePath string) *storeLock {
+	return &storeLock{path: filePath + ".lock"}
+}
+
+func (l *storeLock) Lock() error {
+	if l.depth > 0 {
+		l.depth++
+		return nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("opening lock file: %v", err)
+	}
+
+	deadline := time.Now().Add(storeLockTimeout)
+	for {
+		err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, unix.EWOULDBLOCK) && !errors.Is(err, unix.EINTR) {
+			file.Close()
+			return fmt.Errorf("locking vault: %v", err)
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return vaultBusyError
+		}
+		time.Sleep(storeLockRetry)
+	}
+
+	l.file = file
+	l.depth = 1
+	return nil
+}
+
+func (l *storeLock) Unlock() error {
+	if l.depth == 0 {
+		return nil
+	}
+	l.depth--
+	if l.depth > 0 {
+		return nil
+	}
+
+	// closing the file releases the flock
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,127 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreLockBusy(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	original := storeLockTimeout
+	storeLockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { storeLockTimeout = original })
+
+	holder := newStoreLock(filePath)
+	if err := holder.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	// reentrant for the same holder
+	if err := holder.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	holder.Unlock()
+
+	other := newStoreLock(filePath)
+	if err := other.Lock(); !errors.Is(err, vaultBusyError) {
+		t.Fatalf("expected vault busy, got %v", err)
+	}
+
+	holder.Unlock()
+	if err := other.Lock(); err != nil {
+		t.Fatalf("the lock must be free once released, got %v", err)
+	}
+	other.Unlock()
+}
+
+func TestParallelWritersLoseNothing(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	const writers = 8
+	const entriesPerWriter = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*entriesPerWriter)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every writer is a separate passc invocation with its own lock file handle
+			vault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}}
+			for i := range entriesPerWriter {
+				name := fmt.Sprintf("writer%d-entry%d", w, i)
+				unlock, err := lockVault(vault)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if _, err := vault.Get(name); !errors.Is(err, entryNotFoundError) {
+					errs <- fmt.Errorf("%s: expected not found, got %v", name, err)
+				} else if err := vault.Put(Data{Name: name, Password: name}); err != nil {
+					errs <- err
+				}
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	vault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath}}
+	items, err := vault.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != writers*entriesPerWriter {
+		t.Errorf("expected %d entries, got %d", writers*entriesPerWriter, len(items))
+	}
+}
+
+func TestRekeyAndMigrateTakeStoreLock(t *testing.T) {
+	original := storeLockTimeout
+	storeLockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { storeLockTimeout = original })
+
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	vault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}}
+	if err := vault.Put(Data{Name: "john", Password: "1234"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// an add holding the lock: passwd must wait for it instead of overwriting its write
+	unlock, err := lockVault(vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rekeyStore(filePath, "p4$$w0rd", "New-p4$$w0rd", testKDFParams); !errors.Is(err, vaultBusyError) {
+		t.Errorf("expected vault busy, got %v", err)
+	}
+	if err := vault.Put(Data{Name: "jane", Password: "5678"}); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	if err := rekeyStore(filePath, "p4$$w0rd", "New-p4$$w0rd", testKDFParams); err != nil {
+		t.Fatal(err)
+	}
+	rekeyed := &fileVault{encryptor: &Encryptor{MasterPassword: "New-p4$$w0rd", FilePath: filePath}}
+	if items, err := rekeyed.List(); err != nil || len(items) != 2 {
+		t.Errorf("expected both entries, got %+v %v", items, err)
+	}
+
+	legacyPath := filepath.Join(t.TempDir(), passcStoreFile)
+	writeLegacyStore(t, legacyPath, "p4$$w0rd", `{"name":"John","password":"1234","info":""}`)
+	holder := newStoreLock(legacyPath)
+	if err := holder.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Unlock()
+	if _, _, err := migrateStore(legacyPath, "p4$$w0rd", testKDFParams, BackupConfig{}); !errors.Is(err, vaultBusyError) {
+		t.Errorf("expected vault busy, got %v", err)
+	}
+}
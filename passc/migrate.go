@@ -0,0 +1,164 @@
+package passc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var alreadyMigratedError = errors.New(passcMigrateUpToDateText)
+
+func isLegacyStore(data []byte) bool {
+	return len(data) != 0 && !bytes.HasPrefix(data, formatMagic)
+}
+
+// isLegacyFile reads only the first bytes of path to tell if it is in the legacy layout
+func isLegacyFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(formatMagic))
+	n, err := io.ReadFull(file, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return isLegacyStore(magic[:n]), nil
+}
+
+// readLegacyStore decrypts the original nonce || ciphertext layout keyed with alignPassword
+func readLegacyStore(data []byte, masterPassword string) (string, error) {
+	block, err := aes.NewCipher([]byte(alignPassword(masterPassword)))
+	if err != nil {
+		return "", fmt.Errorf("cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("gcm: %v", err)
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", truncatedFileError
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", invalidPasswordError
+	}
+	return string(plaintext), nil
+}
+
+// legacyFilePath is where a legacy file is kept after being migrated or moved aside
+func legacyFilePath(path string, now time.Time) string {
+	return fmt.Sprintf("%s.legacy-%s", path, now.Format("20060102-150405"))
+}
+
+// migrateStore rewrites a legacy store in the current format. The original file is kept
+// next to it as a timestamped backup. The store.bkp of older versions is migrated too, or moved
+// aside the same way when the master password does not open it, so backups never hold a legacy
+// file. The migrated store gets its first backup generation before the store lock is released, so
+// no other writer can come in between. Returns the number of entries and the backup path
+func migrateStore(filePath, masterPassword string, params KDFParams, retention BackupConfig) (int, string, error) {
+	lock := newStoreLock(filePath)
+	if err := lock.Lock(); err != nil {
+		return 0, "", err
+	}
+	defer lock.Unlock()
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, "", fmt.Errorf("reading store: %v", err)
+	}
+
+	if len(data) == 0 {
+		return 0, "", emptyFile
+	}
+	if !isLegacyStore(data) {
+		return 0, "", alreadyMigratedError
+	}
+
+	now := time.Now()
+	count, backupPath, err := migrateFile(filePath, data, masterPassword, params, now)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := migrateLegacyBackup(filePath, masterPassword, params, now); err != nil {
+		return 0, "", err
+	}
+	if _, err := makeBackUp(filePath, retention); err != nil && !errors.Is(err, emptyFile) {
+		return 0, "", fmt.Errorf("store migrated but backing it up failed: %v", err)
+	}
+	return count, backupPath, nil
+}
+
+func migrateLegacyBackup(filePath, masterPassword string, params KDFParams, now time.Time) error {
+	legacyPath := legacyBackupFilePath(filePath)
+	data, err := os.ReadFile(legacyPath)
+	if err != nil || !isLegacyStore(data) {
+		return nil
+	}
+
+	if _, _, err := migrateFile(legacyPath, data, masterPassword, params, now); err != nil {
+		if err := os.Rename(legacyPath, legacyFilePath(legacyPath, now)); err != nil {
+			return fmt.Errorf("moving legacy backup aside: %v", err)
+		}
+	}
+	return nil
+}
+
+// migrateFile re-encrypts the legacy data of path in the current format, verifies it and only
+// then replaces the file, keeping the original at legacyFilePath
+func migrateFile(path string, data []byte, masterPassword string, params KDFParams, now time.Time) (int, string, error) {
+	legacyContent, err := readLegacyStore(data, masterPassword)
+	if err != nil {
+		return 0, "", err
+	}
+
+	items, err := decodeEntries(legacyContent)
+	if err != nil {
+		return 0, "", err
+	}
+	content, err := encodeEntries(items)
+	if err != nil {
+		return 0, "", fmt.Errorf("converting data to JSON: %v", err)
+	}
+
+	migratingPath := path + ".migrating"
+	encryptor := Encryptor{MasterPassword: masterPassword, FilePath: migratingPath, KDF: params}
+	if err := encryptor.encryptText(content); err != nil {
+		return 0, "", fmt.Errorf("encrypting store: %v", err)
+	}
+	defer os.Remove(migratingPath)
+
+	roundTrip, err := encryptor.readEncryptedText()
+	if err != nil {
+		return 0, "", fmt.Errorf("verifying migrated store: %v", err)
+	}
+	if roundTrip != content {
+		return 0, "", fmt.Errorf("verifying migrated store: content mismatch")
+	}
+
+	backupPath := legacyFilePath(path, now)
+	if err := os.Rename(path, backupPath); err != nil {
+		return 0, "", fmt.Errorf("backing up legacy store: %v", err)
+	}
+	if err := os.Rename(migratingPath, path); err != nil {
+		// put the legacy store back so the user is never left without a vault
+		os.Rename(backupPath, path)
+		return 0, "", fmt.Errorf("replacing store: %v", err)
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return 0, "", err
+	}
+
+	return len(items), backupPath, nil
+}
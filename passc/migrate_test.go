@@ -0,0 +1,142 @@
+package passc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeLegacyStore(t *testing.T, filePath, masterPassword, content string) []byte {
+	t.Helper()
+	block, err := aes.NewCipher([]byte(alignPassword(masterPassword)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+
+	data := append(nonce, gcm.Seal(nil, nonce, []byte(content), nil)...)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMigrateStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	content := `{"name":"John","password":"1234","info":""}|{"name":"Jane","password":"5678","info":"x"}`
+	legacy := writeLegacyStore(t, filePath, "p4$$w0rd", content)
+
+	if _, _, err := migrateStore(filePath, "wr0ng!", testKDFParams, BackupConfig{}); !errors.Is(err, invalidPasswordError) {
+		t.Fatalf("expected invalid password, got %v", err)
+	}
+
+	count, backupPath, err := migrateStore(filePath, "p4$$w0rd", testKDFParams, BackupConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 migrated entries, got %d", count)
+	}
+
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, legacy) {
+		t.Error("legacy backup must keep the original bytes")
+	}
+
+	encryptor := Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath}
+	migrated, err := encryptor.readEncryptedText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := decodeEntries(content)
+	if items, err := decodeEntries(migrated); err != nil || !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %+v, got %q %v", expected, migrated, err)
+	}
+
+	if _, _, err := migrateStore(filePath, "p4$$w0rd", testKDFParams, BackupConfig{}); !errors.Is(err, alreadyMigratedError) {
+		t.Errorf("expected already migrated error, got %v", err)
+	}
+
+	if _, err := os.Stat(filePath + ".migrating"); !os.IsNotExist(err) {
+		t.Error("temporary migration file must be removed")
+	}
+}
+
+func TestMigrateThenAddThenPasswd(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, passcStoreFile)
+	writeLegacyStore(t, filePath, "p4$$w0rd", `{"name":"John","password":"1234","info":""}`)
+	writeLegacyStore(t, legacyBackupFilePath(filePath), "p4$$w0rd", `{"name":"Jane","password":"5678","info":""}`)
+
+	if _, _, err := migrateStore(filePath, "p4$$w0rd", testKDFParams, BackupConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if matches, _ := filepath.Glob(legacyBackupFilePath(filePath) + ".legacy-*"); len(matches) != 1 {
+		t.Errorf("the original store.bkp must be kept, got %v", matches)
+	}
+
+	// the migrated store.bkp is adopted by the backup migrate takes, next to the migrated store
+	backups, err := listBackups(filePath)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("unexpected backups %+v %v", backups, err)
+	}
+	for _, b := range backups {
+		if legacy, err := isLegacyFile(b.Path); err != nil || legacy {
+			t.Errorf("%s: generations must be in the current format, got %v", b.ID, err)
+		}
+	}
+
+	vault := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}}
+	if err := vault.Put(Data{Name: "acme", Password: "0000"}); err != nil {
+		t.Fatal(err)
+	}
+	if backups, err = listBackups(filePath); err != nil || len(backups) != 3 {
+		t.Fatalf("unexpected backups %+v %v", backups, err)
+	}
+
+	if err := rekeyStore(filePath, "p4$$w0rd", "New-p4$$w0rd", testKDFParams); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backups {
+		rekeyed := Encryptor{MasterPassword: "New-p4$$w0rd", FilePath: b.Path}
+		if _, err := rekeyed.readEncryptedText(); err != nil {
+			t.Errorf("%s: %v", b.ID, err)
+		}
+	}
+}
+
+func TestMigrateMovesUnreadableBackupAside(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	writeLegacyStore(t, filePath, "p4$$w0rd", `{"name":"John","password":"1234","info":""}`)
+	legacy := writeLegacyStore(t, legacyBackupFilePath(filePath), "older-p4$$w0rd", `{"name":"Jane","password":"5678","info":""}`)
+
+	if _, _, err := migrateStore(filePath, "p4$$w0rd", testKDFParams, BackupConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacyBackupFilePath(filePath)); !os.IsNotExist(err) {
+		t.Errorf("a store.bkp the master password does not open must be moved aside, got %v", err)
+	}
+	matches, _ := filepath.Glob(legacyBackupFilePath(filePath) + ".legacy-*")
+	if len(matches) != 1 {
+		t.Fatalf("unexpected legacy files %v", matches)
+	}
+	if data, _ := os.ReadFile(matches[0]); !bytes.Equal(data, legacy) {
+		t.Error("the moved store.bkp must keep the original bytes")
+	}
+}
@@ -0,0 +1,137 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// folderSeparator splits path-style names like work/aws/prod into folders
+const folderSeparator = "/"
+
+var entryNameError = errors.New(passcEntryNameErr)
+
+// validateName rejects names that would make empty folders: leading, trailing or double separators
+func validateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: %q", entryNameError, name)
+	}
+	for _, part := range strings.Split(name, folderSeparator) {
+		if strings.TrimSpace(part) == "" {
+			return fmt.Errorf("%w: %s", entryNameError, name)
+		}
+	}
+	return nil
+}
+
+func isFolder(name string) bool {
+	return strings.HasSuffix(name, folderSeparator)
+}
+
+// baseName is the last part of a path-style name: work/aws/prod -> prod
+func baseName(name string) string {
+	return name[strings.LastIndex(name, folderSeparator)+1:]
+}
+
+// inFolder tells if the entry is somewhere below folder. The empty folder holds every entry
+func inFolder(name, folder string) bool {
+	return strings.HasPrefix(name, folder)
+}
+
+// moveTarget returns the new name of an entry moved from src to dst. Moving into a folder
+// (dst ending with /) keeps the entry base name, and moving a folder keeps the path below it
+func moveTarget(name, src, dst string) string {
+	if isFolder(src) {
+		return dst + strings.TrimPrefix(name, src)
+	}
+	if isFolder(dst) {
+		return dst + baseName(name)
+	}
+	return dst
+}
+
+// completeName suggests the next level of names and folders below what was typed so far
+func completeName(items []Data, typed string) []string {
+	folder := typed[:strings.LastIndex(typed, folderSeparator)+1]
+
+	seen := make(map[string]bool)
+	var suggestions []string
+	for _, d := range items {
+		if !strings.HasPrefix(d.Name, typed) {
+			continue
+		}
+		rest := strings.TrimPrefix(d.Name, folder)
+		suggestion := d.Name
+		if i := strings.Index(rest, folderSeparator); i != -1 {
+			suggestion = folder + rest[:i+1]
+		}
+		if !seen[suggestion] {
+			seen[suggestion] = true
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
+// folderNode is a folder of the tree view with its subfolders and entries
+type folderNode struct {
+	folders map[string]*folderNode
+	entries []Data
+}
+
+func newFolderTree(items []Data, folder string) *folderNode {
+	root := &folderNode{folders: make(map[string]*folderNode)}
+	for _, d := range items {
+		if !inFolder(d.Name, folder) {
+			continue
+		}
+		node := root
+		parts := strings.Split(strings.TrimPrefix(d.Name, folder), folderSeparator)
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node.folders[part]
+			if !ok {
+				child = &folderNode{folders: make(map[string]*folderNode)}
+				node.folders[part] = child
+			}
+			node = child
+		}
+		node.entries = append(node.entries, d)
+	}
+	return root
+}
+
+func (n *folderNode) isEmpty() bool {
+	return len(n.folders) == 0 && len(n.entries) == 0
+}
+
+// print renders folders first and then entries, both sorted, with the entry
+// properties below each one
+func (n *folderNode) print(prefix string) {
+	folders := make([]string, 0, len(n.folders))
+	for name := range n.folders {
+		folders = append(folders, name)
+	}
+	sort.Strings(folders)
+	sort.Slice(n.entries, func(i, j int) bool { return n.entries[i].Name < n.entries[j].Name })
+
+	total := len(folders) + len(n.entries)
+	for i, name := range folders {
+		branch, childPrefix := treeBranch(prefix, i == total-1)
+		fmt.Printf("%s\033[1m%s%s\033[0m\n", branch, name, folderSeparator)
+		n.folders[name].print(childPrefix)
+	}
+	for i, d := range n.entries {
+		branch, childPrefix := treeBranch(prefix, len(folders)+i == total-1)
+		fmt.Printf("%s\033[1m%s\033[0m\n", branch, baseName(d.Name))
+		d.printRows(childPrefix)
+	}
+}
+
+func treeBranch(prefix string, isLast bool) (string, string) {
+	if isLast {
+		return prefix + "└── ", prefix + "    "
+	}
+	return prefix + "├── ", prefix + "│   "
+}
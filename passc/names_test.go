@@ -0,0 +1,52 @@
+package passc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"github", "work/aws/prod", "a b/c"} {
+		if err := validateName(name); err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "work/", "/work", "work//aws", "work/ /aws"} {
+		if err := validateName(name); !errors.Is(err, entryNameError) {
+			t.Errorf("%q: expected an invalid name, got %v", name, err)
+		}
+	}
+}
+
+func TestMoveTarget(t *testing.T) {
+	tests := []struct{ name, src, dst, expected string }{
+		{"aws", "aws", "work/aws/prod", "work/aws/prod"},
+		{"github", "github", "personal/", "personal/github"},
+		{"work/aws/prod", "work/aws/prod", "archive/", "archive/prod"},
+		{"work/aws/prod", "work/", "old/work/", "old/work/aws/prod"},
+		{"a/x", "a/", "a/b/", "a/b/x"},
+	}
+	for _, test := range tests {
+		if actual := moveTarget(test.name, test.src, test.dst); actual != test.expected {
+			t.Errorf("mv %s %s: expected %s for %s, got %s", test.src, test.dst, test.expected, test.name, actual)
+		}
+	}
+}
+
+func TestCompleteName(t *testing.T) {
+	items := []Data{{Name: "github"}, {Name: "work/aws/prod"}, {Name: "work/aws/dev"}, {Name: "work/gitlab"}, {Name: "wiki"}}
+
+	tests := map[string][]string{
+		"":          {"github", "wiki", "work/"},
+		"w":         {"wiki", "work/"},
+		"work/":     {"work/aws/", "work/gitlab"},
+		"work/aws/": {"work/aws/dev", "work/aws/prod"},
+		"x":         nil,
+	}
+	for typed, expected := range tests {
+		if actual := completeName(items, typed); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%q: expected %v, got %v", typed, expected, actual)
+		}
+	}
+}
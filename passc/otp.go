@@ -0,0 +1,194 @@
+package passc
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otpTypeTOTP = "totp"
+	otpTypeHOTP = "hotp"
+
+	otpDefaultAlgorithm = "SHA1"
+	otpDefaultDigits    = 6
+	otpDefaultPeriod    = 30
+)
+
+var otpError = errors.New(passcOTPErr)
+
+// OTP is the 2FA seed of an entry: a time based (RFC 6238) or a counter based (RFC 4226) one
+type OTP struct {
+	Type      string `json:"type"`
+	Secret    string `json:"secret"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period,omitempty"`
+	// Counter is the next HOTP counter, saved back after each code
+	Counter uint64 `json:"counter,omitempty"`
+	Issuer  string `json:"issuer,omitempty"`
+	Account string `json:"account,omitempty"`
+}
+
+// newOTP reads an otpauth:// URI or a bare base32 secret, which gets the usual TOTP defaults
+func newOTP(value string) (*OTP, error) {
+	if strings.HasPrefix(value, "otpauth://") {
+		return parseOTPURI(value)
+	}
+
+	otp := &OTP{Type: otpTypeTOTP, Secret: value, Algorithm: otpDefaultAlgorithm, Digits: otpDefaultDigits, Period: otpDefaultPeriod}
+	return otp, otp.validate()
+}
+
+func parseOTPURI(uri string) (*OTP, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", otpError, err)
+	}
+
+	query := u.Query()
+	otp := &OTP{
+		Type:      strings.ToLower(u.Host),
+		Secret:    query.Get("secret"),
+		Algorithm: otpDefaultAlgorithm,
+		Digits:    otpDefaultDigits,
+		Issuer:    query.Get("issuer"),
+	}
+	if otp.Type == otpTypeTOTP {
+		otp.Period = otpDefaultPeriod
+	}
+
+	// the label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		otp.Account = strings.TrimSpace(account)
+		if otp.Issuer == "" {
+			otp.Issuer = issuer
+		}
+	} else {
+		otp.Account = label
+	}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		otp.Algorithm = strings.ToUpper(algorithm)
+	}
+	for param, target := range map[string]*int{"digits": &otp.Digits, "period": &otp.Period} {
+		if value := query.Get(param); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("%w: %s %q", otpError, param, value)
+			}
+		}
+	}
+	if otp.Type == otpTypeHOTP {
+		counter := query.Get("counter")
+		if counter == "" {
+			return nil, fmt.Errorf("%w: hotp needs a counter", otpError)
+		}
+		if otp.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: counter %q", otpError, counter)
+		}
+	}
+
+	return otp, otp.validate()
+}
+
+func (o OTP) validate() error {
+	switch o.Type {
+	case otpTypeTOTP:
+		if o.Period < 1 {
+			return fmt.Errorf("%w: period must be positive", otpError)
+		}
+	case otpTypeHOTP:
+	default:
+		return fmt.Errorf("%w: type must be totp or hotp", otpError)
+	}
+	if _, err := o.hash(); err != nil {
+		return err
+	}
+	if o.Digits < 6 || o.Digits > 10 {
+		return fmt.Errorf("%w: digits must be between 6 and 10", otpError)
+	}
+	if _, err := o.key(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o OTP) key() ([]byte, error) {
+	secret := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(o.Secret))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("%w: secret must be base32", otpError)
+	}
+	return key, nil
+}
+
+func (o OTP) hash() (func() hash.Hash, error) {
+	switch o.Algorithm {
+	case "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: algorithm must be SHA1, SHA256 or SHA512", otpError)
+	}
+}
+
+// code returns the TOTP code at the given time, or the HOTP code of the current counter
+func (o OTP) code(at time.Time) (string, error) {
+	counter := o.Counter
+	if o.Type == otpTypeTOTP {
+		counter = uint64(at.Unix()) / uint64(o.Period)
+	}
+
+	key, err := o.key()
+	if err != nil {
+		return "", err
+	}
+	h, err := o.hash()
+	if err != nil {
+		return "", err
+	}
+	return hotp(h, key, counter, o.Digits), nil
+}
+
+// remaining is how long the TOTP code of the given time stays valid
+func (o OTP) remaining(at time.Time) time.Duration {
+	period := int64(o.Period)
+	return time.Duration(period-at.Unix()%period) * time.Second
+}
+
+func (o OTP) String() string {
+	if o.Type == otpTypeHOTP {
+		return fmt.Sprintf("HOTP %s, %d digits, counter %d", o.Algorithm, o.Digits, o.Counter)
+	}
+	return fmt.Sprintf("TOTP %s, %d digits, every %ds", o.Algorithm, o.Digits, o.Period)
+}
+
+// hotp is the RFC 4226 dynamic truncation of HMAC(key, counter)
+func hotp(h func() hash.Hash, key []byte, counter uint64, digits int) string {
+	mac := hmac.New(h, key)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint64(1)
+	for range digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, uint64(value)%mod)
+}
@@ -0,0 +1,129 @@
+package passc
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	otp := OTP{
+		Type:      otpTypeHOTP,
+		Secret:    base32.StdEncoding.EncodeToString([]byte("12345678901234567890")),
+		Algorithm: "SHA1",
+		Digits:    6,
+	}
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range expected {
+		otp.Counter = uint64(counter)
+		actual, err := otp.code(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != code {
+			t.Errorf("counter %d: expected %s, got %s", counter, code, actual)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B
+	seeds := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		time  int64
+		codes map[string]string
+	}{
+		{59, map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{1111111109, map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{1111111111, map[string]string{"SHA1": "14050471", "SHA256": "67062674", "SHA512": "99943326"}},
+		{1234567890, map[string]string{"SHA1": "89005924", "SHA256": "91819424", "SHA512": "93441116"}},
+		{2000000000, map[string]string{"SHA1": "69279037", "SHA256": "90698825", "SHA512": "38618901"}},
+		{20000000000, map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	}
+
+	for _, test := range tests {
+		for algorithm, code := range test.codes {
+			otp := OTP{
+				Type:      otpTypeTOTP,
+				Secret:    base32.StdEncoding.EncodeToString([]byte(seeds[algorithm])),
+				Algorithm: algorithm,
+				Digits:    8,
+				Period:    30,
+			}
+			actual, err := otp.code(time.Unix(test.time, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != code {
+				t.Errorf("%s at %d: expected %s, got %s", algorithm, test.time, code, actual)
+			}
+		}
+	}
+}
+
+func TestOTPRemaining(t *testing.T) {
+	otp := OTP{Type: otpTypeTOTP, Period: 30}
+	if left := otp.remaining(time.Unix(59, 0)); left != time.Second {
+		t.Errorf("expected 1s, got %s", left)
+	}
+	if left := otp.remaining(time.Unix(60, 0)); left != 30*time.Second {
+		t.Errorf("expected 30s, got %s", left)
+	}
+}
+
+func TestNewOTP(t *testing.T) {
+	otp, err := newOTP("otpauth://totp/ACME%20Co:john@example.com?secret=JBSWY3DPEHPK3PXP&algorithm=sha256&digits=8&period=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := OTP{
+		Type:      otpTypeTOTP,
+		Secret:    "JBSWY3DPEHPK3PXP",
+		Algorithm: "SHA256",
+		Digits:    8,
+		Period:    60,
+		Issuer:    "ACME Co",
+		Account:   "john@example.com",
+	}
+	if *otp != expected {
+		t.Errorf("expected %+v, got %+v", expected, *otp)
+	}
+
+	otp, err = newOTP("otpauth://hotp/john?secret=JBSWY3DPEHPK3PXP&issuer=ACME&counter=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.Type != otpTypeHOTP || otp.Counter != 7 || otp.Issuer != "ACME" || otp.Account != "john" || otp.Period != 0 {
+		t.Errorf("unexpected hotp %+v", *otp)
+	}
+
+	// a bare secret gets the usual authenticator app defaults
+	otp, err = newOTP("jbsw y3dp ehpk 3pxp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.Type != otpTypeTOTP || otp.Algorithm != "SHA1" || otp.Digits != 6 || otp.Period != 30 {
+		t.Errorf("unexpected defaults %+v", *otp)
+	}
+
+	for _, invalid := range []string{
+		"not base32!",
+		"otpauth://totp/john?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/john?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/john?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://hotp/john?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://motp/john?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/john",
+	} {
+		if _, err := newOTP(invalid); !errors.Is(err, otpError) {
+			t.Errorf("%s: expected otp error, got %v", invalid, err)
+		}
+	}
+}
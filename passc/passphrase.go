@@ -0,0 +1,116 @@
+package passc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// effWordlist is the EFF large wordlist for five dice: "11111\tabacus" per line. It is CC BY 3.0 US,
+// see wordlists/NOTICE-eff.txt
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed wordlists/eff_large_wordlist.txt
+var effWordlist string
+
+var dicewareWords = sync.OnceValue(func() []string {
+	var words []string
+	for line := range strings.Lines(effWordlist) {
+		if _, word, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// PassphrasePolicy tells how passphrases are generated
+type PassphrasePolicy struct {
+	Words     int    `json:"words"`
+	Separator string `json:"separator"`
+	// Capitalize writes the first letter of every word in uppercase
+	Capitalize bool `json:"capitalize,omitempty"`
+	// Digit and Symbol append a random digit or symbol to a random word, for sites requiring them
+	Digit  bool `json:"digit,omitempty"`
+	Symbol bool `json:"symbol,omitempty"`
+}
+
+func defaultPassphrasePolicy() PassphrasePolicy {
+	return PassphrasePolicy{Words: 6, Separator: "-"}
+}
+
+// UnmarshalJSON keeps the defaults of the keys left out in config.json
+func (p *PassphrasePolicy) UnmarshalJSON(b []byte) error {
+	type passphrasePolicy PassphrasePolicy
+	value := passphrasePolicy(defaultPassphrasePolicy())
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	*p = PassphrasePolicy(value)
+	return nil
+}
+
+func (p PassphrasePolicy) validate() error {
+	if p.Words < 1 {
+		return fmt.Errorf("%w: words must be positive", policyError)
+	}
+	return nil
+}
+
+// Entropy counts the words and where and which digit or symbol was added. Capitalizing every word
+// adds nothing
+func (p PassphrasePolicy) Entropy() float64 {
+	bits := float64(p.Words) * math.Log2(float64(len(dicewareWords())))
+	if p.Digit {
+		bits += math.Log2(float64(len(passcCharsetNumeric))) + math.Log2(float64(p.Words))
+	}
+	if p.Symbol {
+		bits += math.Log2(float64(len(passcCharsetSymbols))) + math.Log2(float64(p.Words))
+	}
+	return bits
+}
+
+// generatePassphrase is diceware with randomIndex instead of dice
+func generatePassphrase(p PassphrasePolicy) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	wordlist := dicewareWords()
+	words := make([]string, p.Words)
+	for i := range words {
+		index, err := randomIndex(len(wordlist))
+		if err != nil {
+			return "", err
+		}
+		words[i] = wordlist[index]
+		if p.Capitalize {
+			runes := []rune(words[i])
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+	}
+
+	for _, extra := range []struct {
+		enabled bool
+		chars   string
+	}{{p.Digit, passcCharsetNumeric}, {p.Symbol, passcCharsetSymbols}} {
+		if !extra.enabled {
+			continue
+		}
+		word, err := randomIndex(len(words))
+		if err != nil {
+			return "", err
+		}
+		char, err := randomIndex(len(extra.chars))
+		if err != nil {
+			return "", err
+		}
+		words[word] += string(extra.chars[char])
+	}
+
+	return strings.Join(words, p.Separator), nil
+}
@@ -0,0 +1,80 @@
+package passc
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestDicewareWords(t *testing.T) {
+	words := dicewareWords()
+	// five dice
+	if len(words) != 7776 {
+		t.Fatalf("expected 7776 words, got %d", len(words))
+	}
+	if words[0] != "abacus" || words[len(words)-1] != "zoom" {
+		t.Errorf("unexpected words %s ... %s", words[0], words[len(words)-1])
+	}
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if seen[word] {
+			t.Errorf("%s is repeated", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	words := make(map[string]bool)
+	for _, word := range dicewareWords() {
+		words[word] = true
+	}
+
+	// a few words like t-shirt have a dash, the default separator
+	phrase, err := generatePassphrase(PassphrasePolicy{Words: 6, Separator: " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(phrase, " ")
+	if len(parts) != 6 {
+		t.Fatalf("expected 6 words, got %q", phrase)
+	}
+	for _, part := range parts {
+		if !words[part] {
+			t.Errorf("%s is not in the wordlist", part)
+		}
+	}
+
+	policy := PassphrasePolicy{Words: 4, Separator: " ", Capitalize: true, Digit: true, Symbol: true}
+	for range 50 {
+		phrase, err := generatePassphrase(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := strings.Split(phrase, " ")
+		if len(parts) != 4 || !strings.ContainsAny(phrase, passcCharsetNumeric) || !strings.ContainsAny(phrase, passcCharsetSymbols) {
+			t.Fatalf("unexpected passphrase %q", phrase)
+		}
+		for _, part := range parts {
+			if !unicode.IsUpper([]rune(part)[0]) {
+				t.Errorf("%s is not capitalized", part)
+			}
+		}
+	}
+
+	if _, err := generatePassphrase(PassphrasePolicy{Words: 0}); !errors.Is(err, policyError) {
+		t.Errorf("expected policy error, got %v", err)
+	}
+}
+
+func TestPassphraseEntropy(t *testing.T) {
+	if bits := defaultPassphrasePolicy().Entropy(); math.Abs(bits-6*math.Log2(7776)) > 1e-9 {
+		t.Errorf("unexpected entropy %v", bits)
+	}
+	withDigit := PassphrasePolicy{Words: 6, Digit: true}.Entropy()
+	if math.Abs(withDigit-defaultPassphrasePolicy().Entropy()-math.Log2(60)) > 1e-9 {
+		t.Errorf("unexpected entropy %v", withDigit)
+	}
+}
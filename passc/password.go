@@ -1,41 +1,19 @@
 package passc
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/howeyc/gopass"
-	"github.com/javiorfo/nilo"
 )
 
 var masterPasswordError = errors.New(passcMasterPasswordLenErr)
 
-func generateRandomPasswordDefault() (*string, error) {
-	return generateRandomPassword(nilo.Nil[int](), nilo.Nil[string]())
-}
-
-func generateRandomPassword(size nilo.Option[int], charsetStr nilo.Option[string]) (*string, error) {
-	length := size.Or(20)
-	charset := charsetStr.Or(passcCharset)
-	password := make([]byte, length)
-
-	_, err := rand.Read(password)
-	if err != nil {
-		return nil, err
-	}
-
-	for i := range length {
-		password[i] = charset[int(password[i])%len(charset)]
-	}
-
-	str := string(password)
-	return &str, nil
-}
-
+// alignPassword is the key scheme of stores created before Argon2id was introduced
 func alignPassword(password string) string {
 	length := len(password)
 	if length < 16 {
@@ -47,37 +25,53 @@ func alignPassword(password string) string {
 	return password
 }
 
+func readMasterPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	bytePassword, err := gopass.GetPasswdMasked()
+	if err != nil {
+		return "", fmt.Errorf("%v", err)
+	}
+	if len(bytePassword) < 6 {
+		return "", masterPasswordError
+	}
+	return string(bytePassword), nil
+}
+
 func checkMasterPassword() (*Encryptor, error) {
-	homeDir, err := os.UserHomeDir()
+	filePath, err := storeFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := loadConfig()
 	if err != nil {
-		return nil, fmt.Errorf("finding user home dir: %v", err)
+		return nil, err
 	}
 
-	dirPath := filepath.Join(homeDir, passcDirFolder)
-	filePath := filepath.Join(dirPath, passcStoreFile)
+	dirPath := filepath.Dir(filePath)
 
-	var masterPassword string
 	_, errFile := os.Stat(filePath)
 
+	encryptor := &Encryptor{FilePath: filePath, KDF: config.KDF}
+
 	if os.IsNotExist(errFile) {
 		fmt.Println(passcInitTitle)
 	} else {
-		encryptorFromTemp := getTempEncryptor(filePath)
-		if encryptorFromTemp.IsValue() {
-			return encryptorFromTemp.AsPtr(), nil
+		agentKey := getAgentKey(filePath)
+		if agentKey.IsValue() {
+			encryptor.key = agentKey.AsPtr()
+			if _, err := encryptor.unlock(); err == nil {
+				return encryptor, nil
+			}
+			encryptor.key = nil
 		}
 		fmt.Println(passcLoginTitle)
 	}
 
-	fmt.Print(passcMasterPasswordText)
-	bytePassword, err := gopass.GetPasswdMasked()
+	masterPassword, err := readMasterPassword(passcMasterPasswordText)
 	if err != nil {
-		return nil, fmt.Errorf("%v", err)
-	}
-	if len(bytePassword) < 6 {
-		return nil, masterPasswordError
+		return nil, err
 	}
-	masterPassword = alignPassword(string(bytePassword))
 
 	if errFile != nil {
 		err = os.MkdirAll(dirPath, 0755)
@@ -85,13 +79,27 @@ func checkMasterPassword() (*Encryptor, error) {
 			return nil, fmt.Errorf("creating directory: %v", err)
 		}
 
-		file, err := os.Create(filePath)
+		// never truncate: another passc command may have created the store meanwhile
+		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0644)
 		if err != nil {
 			return nil, fmt.Errorf("creating file: %v", err)
 		}
 		defer file.Close()
 	}
 
-	newTemp(masterPassword)
-	return &Encryptor{MasterPassword: masterPassword, FilePath: filePath}, nil
+	encryptor.MasterPassword = masterPassword
+	if err := startSession(encryptor, config.Session); err != nil && !errors.Is(err, invalidPasswordError) {
+		// a wrong password is reported by the first read of the store, as without an agent
+		log.Println("starting session: ", err.Error())
+	}
+	return encryptor, nil
+}
+
+// startSession unlocks the store and hands its key to the agent. A wrong password starts nothing
+func startSession(encryptor *Encryptor, session SessionConfig) error {
+	key, err := encryptor.unlock()
+	if err != nil {
+		return err
+	}
+	return setAgentKey(encryptor.FilePath, *key, session)
 }
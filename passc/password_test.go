@@ -2,8 +2,6 @@ package passc
 
 import (
 	"testing"
-
-	"github.com/javiorfo/nilo"
 )
 
 func TestAlignPassword(t *testing.T) {
@@ -17,49 +15,3 @@ func TestAlignPassword(t *testing.T) {
 		t.Fatal("length must be 16")
 	}
 }
-
-func TestGenerateRandomPassword(t *testing.T) {
-	tests := []struct {
-		name     string
-		size     nilo.Option[int]
-		charset  nilo.Option[string]
-		expected int
-	}{
-		{
-			name:     "default size and charset",
-			size:     nilo.Nil[int](),
-			charset:  nilo.Nil[string](),
-			expected: 20,
-		},
-		{
-			name:     "custom size",
-			size:     nilo.Value(10),
-			charset:  nilo.Nil[string](),
-			expected: 10,
-		},
-		{
-			name:     "custom charset",
-			size:     nilo.Nil[int](),
-			charset:  nilo.Value("abcdefghijklmnopqrstuvwxyz"),
-			expected: 20,
-		},
-		{
-			name:     "custom size and charset",
-			size:     nilo.Value(15),
-			charset:  nilo.Value("abcdefghijklmnopqrstuvwxyz"),
-			expected: 15,
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			password, err := generateRandomPassword(test.size, test.charset)
-			if err != nil {
-				t.Errorf("expected no error, got %v", err)
-			}
-			if len(*password) != test.expected {
-				t.Errorf("expected password length %d, got %d", test.expected, len(*password))
-			}
-		})
-	}
-}
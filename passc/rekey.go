@@ -0,0 +1,126 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode"
+)
+
+var (
+	passwordMismatchError  = errors.New(passcPasswordMismatchErr)
+	passwordUnchangedError = errors.New(passcSamePasswordErr)
+	passwordWeakError      = errors.New(passcMasterPasswordWeakErr)
+)
+
+// checkNewMasterPassword requires at least 10 characters from 3 classes (lower, upper, digit, symbol),
+// or 16 characters of anything for passphrases
+func checkNewMasterPassword(oldPassword, newPassword, confirmation string) error {
+	if newPassword != confirmation {
+		return passwordMismatchError
+	}
+	if newPassword == oldPassword {
+		return passwordUnchangedError
+	}
+
+	length := len([]rune(newPassword))
+	if length >= 16 {
+		return nil
+	}
+	if length < 10 {
+		return passwordWeakError
+	}
+
+	var lower, upper, digit, symbol int
+	for _, r := range newPassword {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	if lower+upper+digit+symbol < 3 {
+		return passwordWeakError
+	}
+	return nil
+}
+
+type rekeyFile struct {
+	path    string
+	tmpPath string
+}
+
+// rekeyStore re-encrypts the store and every backup generation with the new master password.
+// All files are fully written and verified before any of them is replaced, under the store lock so
+// no other passc writes the store or a backup with the old password meanwhile
+func rekeyStore(filePath, oldPassword, newPassword string, params KDFParams) error {
+	lock := newStoreLock(filePath)
+	if err := lock.Lock(); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	var backupPaths []string
+	backups, err := listBackups(filePath)
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		backupPaths = append(backupPaths, b.Path)
+	}
+	if stat, err := os.Stat(legacyBackupFilePath(filePath)); err == nil && stat.Size() != 0 {
+		backupPaths = append(backupPaths, legacyBackupFilePath(filePath))
+	}
+
+	// backups in the legacy layout, adopted by older versions, are left for passc migrate
+	paths := []string{filePath}
+	for _, path := range backupPaths {
+		if legacy, err := isLegacyFile(path); err != nil || !legacy {
+			paths = append(paths, path)
+		}
+	}
+
+	var files []rekeyFile
+	defer func() {
+		for _, f := range files {
+			os.Remove(f.tmpPath)
+		}
+	}()
+
+	for _, path := range paths {
+		old := Encryptor{MasterPassword: oldPassword, FilePath: path}
+		content, err := old.readEncryptedText()
+		if err != nil {
+			return fmt.Errorf("decrypting %s: %w", path, err)
+		}
+
+		tmp := Encryptor{MasterPassword: newPassword, FilePath: path + ".rekey", KDF: params}
+		files = append(files, rekeyFile{path: path, tmpPath: tmp.FilePath})
+		if err := tmp.encryptText(content); err != nil {
+			return fmt.Errorf("encrypting %s: %v", path, err)
+		}
+
+		roundTrip, err := tmp.readEncryptedText()
+		if err != nil || roundTrip != content {
+			return fmt.Errorf("verifying %s: re-encrypted content mismatch", path)
+		}
+	}
+
+	for _, f := range files {
+		if err := os.Rename(f.tmpPath, f.path); err != nil {
+			return fmt.Errorf("replacing %s: %v", f.path, err)
+		}
+	}
+	if len(backups) != 0 {
+		if err := syncDir(backupDir(filePath)); err != nil {
+			return err
+		}
+	}
+	return syncDir(filepath.Dir(filePath))
+}
@@ -0,0 +1,92 @@
+package passc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckNewMasterPassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		newPassword  string
+		confirmation string
+		expected     error
+	}{
+		{name: "mismatch", newPassword: "Str0ng-Passw0rd", confirmation: "Str0ng-Passw0rD", expected: passwordMismatchError},
+		{name: "unchanged", newPassword: "old-p4$$w0rd", confirmation: "old-p4$$w0rd", expected: passwordUnchangedError},
+		{name: "too short", newPassword: "Ab1!xyz", confirmation: "Ab1!xyz", expected: passwordWeakError},
+		{name: "two classes", newPassword: "abcdefgh12", confirmation: "abcdefgh12", expected: passwordWeakError},
+		{name: "three classes", newPassword: "abcdefgH12", confirmation: "abcdefgH12", expected: nil},
+		{name: "long passphrase", newPassword: "correct horse battery", confirmation: "correct horse battery", expected: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkNewMasterPassword("old-p4$$w0rd", test.newPassword, test.confirmation)
+			if !errors.Is(err, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestRekeyStore(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, passcStoreFile)
+	legacyBackupPath := filepath.Join(dir, passcBackUpFile)
+
+	legacyBackup := Encryptor{MasterPassword: "old-p4$$w0rd", FilePath: legacyBackupPath, KDF: testKDFParams}
+	if err := legacyBackup.encryptText(`{"name":"bob","password":"0000","info":""}`); err != nil {
+		t.Fatal(err)
+	}
+	store := Encryptor{MasterPassword: "old-p4$$w0rd", FilePath: filePath, KDF: testKDFParams}
+	if err := store.encryptText(`{"name":"jane","password":"5678","info":""}`); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := makeBackUp(filePath, BackupConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.encryptText(`{"name":"john","password":"1234","info":""}`); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := listBackups(filePath)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("expected the legacy backup adopted next to the new one, got %+v %v", backups, err)
+	}
+
+	if err := rekeyStore(filePath, "wr0ng-p4$$w0rd", "New-p4$$w0rd", testKDFParams); err == nil {
+		t.Fatal("rekey with a wrong current password must fail")
+	}
+
+	if err := rekeyStore(filePath, "old-p4$$w0rd", "New-p4$$w0rd", testKDFParams); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		filePath:        `{"name":"john","password":"1234","info":""}`,
+		backup.Path:     `{"name":"jane","password":"5678","info":""}`,
+		backups[1].Path: `{"name":"bob","password":"0000","info":""}`,
+	} {
+		old := Encryptor{MasterPassword: "old-p4$$w0rd", FilePath: path}
+		if _, err := old.readEncryptedText(); !errors.Is(err, invalidPasswordError) {
+			t.Errorf("%s: old password must no longer work, got %v", path, err)
+		}
+
+		rekeyed := Encryptor{MasterPassword: "New-p4$$w0rd", FilePath: path}
+		content, err := rekeyed.readEncryptedText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, content)
+		}
+
+		if _, err := os.Stat(path + ".rekey"); !os.IsNotExist(err) {
+			t.Errorf("%s: temporary file must be removed", path)
+		}
+	}
+}
@@ -0,0 +1,825 @@
+package passc
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// The estimator follows zxcvbn: the password is split into the sequence of patterns (dictionary
+// words, keyboard walks, repeats, sequences, dates and bruteforce) that is cheapest to guess, and
+// the guesses of the patterns are multiplied. The ranked word lists come from zxcvbn (MIT, see
+// wordlists/LICENSE-zxcvbn.txt)
+
+//go:embed wordlists/passwords.txt wordlists/english.txt wordlists/names.txt wordlists/surnames.txt
+var wordlists embed.FS
+
+const (
+	patternDictionary = "dictionary"
+	patternSpatial    = "spatial"
+	patternRepeat     = "repeat"
+	patternSequence   = "sequence"
+	patternDate       = "date"
+	patternBruteforce = "bruteforce"
+
+	// only the beginning of very long passwords is analyzed, they are strong anyway
+	strengthMaxLength = 100
+	// guesses per second of an offline attack against a slow hash
+	strengthGuessRate = 1e4
+
+	bruteforceCardinality = 10
+	minGuessesSingleChar  = 10
+	minGuessesMultiChar   = 50
+	minGuessesSequence    = 1e4
+	minYearSpace          = 20
+)
+
+// Strength is the estimation of how hard a password is to guess
+type Strength struct {
+	// Score goes from 0 (too guessable) to 4 (very unguessable)
+	Score   int     `json:"score"`
+	Guesses float64 `json:"guesses"`
+	Warning string  `json:"warning,omitempty"`
+}
+
+// Bits is the entropy equivalent to the estimated guesses
+func (s Strength) Bits() float64 {
+	return math.Log2(s.Guesses)
+}
+
+// CrackTime is the estimated time of an offline attack
+func (s Strength) CrackTime() string {
+	return displayTime(s.Guesses / strengthGuessRate)
+}
+
+type match struct {
+	pattern string
+	i, j    int
+	token   string
+	guesses float64
+	// dictionary is the word list of dictionary matches and rank the position of the word in it
+	dictionary string
+	rank       int
+}
+
+// estimateStrength scores the password. Inputs like the entry name or username count as the most
+// common words
+func estimateStrength(password string, inputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{Guesses: 1, Warning: "Empty password"}
+	}
+	if len(runes) > strengthMaxLength {
+		runes = runes[:strengthMaxLength]
+	}
+
+	sequence, guesses := mostGuessableSequence(runes, findMatches(runes, inputs))
+	return Strength{Score: guessesScore(guesses), Guesses: guesses, Warning: strengthWarning(sequence, guessesScore(guesses))}
+}
+
+func guessesScore(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func findMatches(runes []rune, inputs []string) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, inputs)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	return matches
+}
+
+// mostGuessableSequence finds the cheapest split of the password into matches and bruteforce
+// segments. Longer splits pay l! to account for the order of the patterns
+func mostGuessableSequence(runes []rune, matches []match) ([]match, float64) {
+	n := len(runes)
+	byEnd := make([][]match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	type step struct {
+		match   match
+		product float64
+		guesses float64
+	}
+	// optimal[k][l] is the best split of runes[:k+1] made of l matches
+	optimal := make([]map[int]step, n)
+	for k := range optimal {
+		optimal[k] = make(map[int]step)
+	}
+
+	update := func(m match, l int) {
+		k := m.j
+		product := m.guesses
+		if l > 1 {
+			product *= optimal[m.i-1][l-1].product
+		}
+		guesses := factorial(l)*product + math.Pow(minGuessesSequence, float64(l-1))
+		for other, s := range optimal[k] {
+			if other <= l && s.guesses <= guesses {
+				return
+			}
+		}
+		optimal[k][l] = step{match: m, product: product, guesses: guesses}
+	}
+	bruteforce := func(i, j int) match {
+		return match{pattern: patternBruteforce, i: i, j: j, token: string(runes[i : j+1]), guesses: bruteforceGuesses(j - i + 1)}
+	}
+
+	for k := range n {
+		for _, m := range byEnd[k] {
+			if m.i == 0 {
+				update(m, 1)
+				continue
+			}
+			for l := range optimal[m.i-1] {
+				update(m, l+1)
+			}
+		}
+
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			for l, s := range optimal[i-1] {
+				// two bruteforce segments in a row are always worse than one
+				if s.match.pattern != patternBruteforce {
+					update(bruteforce(i, k), l+1)
+				}
+			}
+		}
+	}
+
+	best, bestGuesses := 0, math.Inf(1)
+	for l, s := range optimal[n-1] {
+		if s.guesses < bestGuesses {
+			best, bestGuesses = l, s.guesses
+		}
+	}
+
+	sequence := make([]match, best)
+	for k, l := n-1, best; l > 0; l-- {
+		m := optimal[k][l].match
+		sequence[l-1] = m
+		k = m.i - 1
+	}
+	return sequence, bestGuesses
+}
+
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	if length == 1 {
+		return max(guesses+1, minGuessesSingleChar+1)
+	}
+	return max(guesses+1, minGuessesMultiChar+1)
+}
+
+// estimateGuesses is the guesses of a part of the password, used by repeats to price the base
+func estimateGuesses(token string) float64 {
+	runes := []rune(token)
+	_, guesses := mostGuessableSequence(runes, findMatches(runes, nil))
+	return guesses
+}
+
+func strengthWarning(sequence []match, score int) string {
+	if score > 2 {
+		return ""
+	}
+	if len(sequence) == 0 {
+		return "Add more words or characters"
+	}
+
+	// the longest pattern explains best why the password is weak
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+
+	switch longest.pattern {
+	case patternDictionary:
+		switch {
+		case longest.dictionary == "passwords" && longest.rank <= 10:
+			return "This is a top-10 common password"
+		case longest.dictionary == "passwords":
+			return "This is a very common password"
+		case longest.dictionary == "inputs":
+			return "Avoid the name of the entry or the username"
+		case longest.dictionary == "names" || longest.dictionary == "surnames":
+			return "Names and surnames are easy to guess"
+		default:
+			return "A word by itself is easy to guess"
+		}
+	case patternSpatial:
+		return "Keyboard patterns like qwerty are easy to guess"
+	case patternRepeat:
+		return "Repeats like \"aaa\" or \"abcabc\" are easy to guess"
+	case patternSequence:
+		return "Sequences like abc or 6543 are easy to guess"
+	case patternDate:
+		return "Dates and years are easy to guess"
+	default:
+		return "Add more words or characters"
+	}
+}
+
+func displayTime(seconds float64) string {
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"year", 365.25 * 24 * 3600},
+		{"month", 30.4 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	if seconds < 1 {
+		return "less than a second"
+	}
+	if seconds >= 100*units[0].seconds {
+		return "centuries"
+	}
+	for _, unit := range units {
+		if seconds >= unit.seconds {
+			count := int(math.Round(seconds / unit.seconds))
+			if count == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", count, unit.name)
+		}
+	}
+	return "less than a second"
+}
+
+// dictionaries
+
+type rankedDictionary struct {
+	name   string
+	ranks  map[string]int
+	maxLen int
+}
+
+var (
+	dictionariesOnce sync.Once
+	dictionaries     []rankedDictionary
+)
+
+func loadDictionaries() []rankedDictionary {
+	dictionariesOnce.Do(func() {
+		for _, name := range []string{"passwords", "english", "names", "surnames"} {
+			content, err := wordlists.ReadFile("wordlists/" + name + ".txt")
+			if err != nil {
+				panic(err)
+			}
+			dictionaries = append(dictionaries, newRankedDictionary(name, strings.Fields(string(content))))
+		}
+	})
+	return dictionaries
+}
+
+func newRankedDictionary(name string, words []string) rankedDictionary {
+	dictionary := rankedDictionary{name: name, ranks: make(map[string]int, len(words))}
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if _, ok := dictionary.ranks[word]; ok || word == "" {
+			continue
+		}
+		dictionary.ranks[word] = i + 1
+		dictionary.maxLen = max(dictionary.maxLen, len([]rune(word)))
+	}
+	return dictionary
+}
+
+// l33tTable maps the substitutions of l33t speak back to letters. Ambiguous ones are tried both ways
+var l33tTable = []map[rune]rune{
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '{': 'c', '[': 'c', '<': 'c', '3': 'e', '6': 'g', '9': 'g', '1': 'i', '!': 'i', '|': 'i', '0': 'o', '$': 's', '5': 's', '+': 't', '7': 't', '%': 'x', '2': 'z'},
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '{': 'c', '[': 'c', '<': 'c', '3': 'e', '6': 'g', '9': 'g', '1': 'l', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's', '+': 't', '7': 'l', '%': 'x', '2': 'z'},
+}
+
+func dictionaryMatches(runes []rune, inputs []string) []match {
+	all := loadDictionaries()
+	if len(inputs) > 0 {
+		var words []string
+		for _, input := range inputs {
+			if input = strings.TrimSpace(input); input != "" {
+				words = append(words, input, baseName(input))
+			}
+		}
+		all = append(all[:len(all):len(all)], newRankedDictionary("inputs", words))
+	}
+
+	lower := []rune(strings.ToLower(string(runes)))
+	var matches []match
+	for _, dictionary := range all {
+		matches = append(matches, matchDictionary(runes, lower, dictionary)...)
+
+		// reversed words, mapped back to the positions of the password
+		reversed := reverseRunes(lower)
+		for _, m := range matchDictionary(reverseRunes(runes), reversed, dictionary) {
+			m.i, m.j = len(runes)-1-m.j, len(runes)-1-m.i
+			m.token = string(runes[m.i : m.j+1])
+			m.guesses *= 2
+			matches = append(matches, m)
+		}
+
+		for _, table := range l33tTable {
+			unleet := make([]rune, len(lower))
+			for i, r := range lower {
+				if letter, ok := table[r]; ok {
+					unleet[i] = letter
+				} else {
+					unleet[i] = r
+				}
+			}
+			for _, m := range matchDictionary(runes, unleet, dictionary) {
+				variations := l33tVariations(lower[m.i:m.j+1], unleet[m.i:m.j+1])
+				if variations > 1 {
+					m.guesses *= variations
+					matches = append(matches, m)
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func matchDictionary(runes, lower []rune, dictionary rankedDictionary) []match {
+	var matches []match
+	for i := range lower {
+		for j := i; j < len(lower) && j-i < dictionary.maxLen; j++ {
+			rank, ok := dictionary.ranks[string(lower[i:j+1])]
+			if !ok {
+				continue
+			}
+			token := string(runes[i : j+1])
+			guesses := float64(rank) * uppercaseVariations(token)
+			if j > i {
+				guesses = max(guesses, minGuessesMultiChar)
+			} else {
+				guesses = max(guesses, minGuessesSingleChar)
+			}
+			matches = append(matches, match{pattern: patternDictionary, i: i, j: j, token: token, guesses: guesses, dictionary: dictionary.name, rank: rank})
+		}
+	}
+	return matches
+}
+
+func reverseRunes(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	return reversed
+}
+
+// uppercaseVariations counts the ways of capitalizing the word, with the common ones
+// (first letter, last letter, all of them) counting as a single extra guess
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+
+	runes := []rune(token)
+	firstOnly := unicode.IsUpper(runes[0]) && upper == 1
+	lastOnly := unicode.IsUpper(runes[len(runes)-1]) && upper == 1
+	if lower == 0 || firstOnly || lastOnly {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// l33tVariations counts the ways of mixing substituted and plain letters
+func l33tVariations(token, unleet []rune) float64 {
+	variations := 1.0
+	for _, letter := range uniqueRunes(unleet) {
+		substituted, plain := 0, 0
+		for i, r := range token {
+			if unleet[i] != letter {
+				continue
+			}
+			if r == letter {
+				plain++
+			} else {
+				substituted++
+			}
+		}
+		if substituted == 0 {
+			continue
+		}
+		if plain == 0 {
+			variations *= 2
+			continue
+		}
+		sum := 0.0
+		for i := 1; i <= min(substituted, plain); i++ {
+			sum += binomial(substituted+plain, i)
+		}
+		variations *= sum
+	}
+	return variations
+}
+
+func uniqueRunes(runes []rune) []rune {
+	seen := make(map[rune]bool)
+	var unique []rune
+	for _, r := range runes {
+		if !seen[r] {
+			seen[r] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// keyboard walks
+
+type keyboard struct {
+	// keys maps each key, shifted or not, to its position
+	keys map[rune][2]int
+	// shifted keys add variations like uppercase letters
+	shifted map[rune]bool
+	// startingPositions and averageDegree size the guesses of a walk
+	startingPositions float64
+	averageDegree     float64
+	// slanted keyboards have each row shifted half a key from the one above
+	slanted bool
+}
+
+var keyboards = []*keyboard{
+	// rows are indented so that a key sits below the keys x and x+1 of the row above
+	newKeyboard([]string{"`1234567890-=", " qwertyuiop[]\\", " asdfghjkl;'", " zxcvbnm,./"}, []string{"~!@#$%^&*()_+", " QWERTYUIOP{}|", " ASDFGHJKL:\"", " ZXCVBNM<>?"}, true),
+	newKeyboard([]string{" /*-", "789+", "456", "123", " 0."}, nil, false),
+}
+
+func newKeyboard(rows, shiftedRows []string, slanted bool) *keyboard {
+	k := &keyboard{keys: make(map[rune][2]int), shifted: make(map[rune]bool), slanted: slanted}
+	for y, row := range rows {
+		for x, r := range row {
+			if r != ' ' {
+				k.keys[r] = [2]int{x, y}
+			}
+		}
+	}
+	for y, row := range shiftedRows {
+		for x, r := range row {
+			if r != ' ' {
+				k.keys[r] = [2]int{x, y}
+				k.shifted[r] = true
+			}
+		}
+	}
+
+	positions := make(map[[2]int]bool)
+	for _, position := range k.keys {
+		positions[position] = true
+	}
+	degrees := 0
+	for position := range positions {
+		for other := range positions {
+			if _, ok := k.direction(position, other); ok {
+				degrees++
+			}
+		}
+	}
+	k.startingPositions = float64(len(positions))
+	k.averageDegree = float64(degrees) / float64(len(positions))
+	return k
+}
+
+// direction tells if two positions are neighbors and which way the walk goes
+func (k *keyboard) direction(from, to [2]int) ([2]int, bool) {
+	dx, dy := to[0]-from[0], to[1]-from[1]
+	if dy < -1 || dy > 1 || (dx == 0 && dy == 0) {
+		return [2]int{}, false
+	}
+	if k.slanted {
+		// a key touches two keys of the row above (x and x+1) and two of the row below (x-1 and x)
+		switch dy {
+		case 0:
+			return [2]int{dx, dy}, dx == 1 || dx == -1
+		case -1:
+			return [2]int{dx, dy}, dx == 0 || dx == 1
+		default:
+			return [2]int{dx, dy}, dx == 0 || dx == -1
+		}
+	}
+	return [2]int{dx, dy}, dx >= -1 && dx <= 1
+}
+
+func spatialMatches(runes []rune) []match {
+	var matches []match
+	for _, k := range keyboards {
+		i := 0
+		for i < len(runes)-2 {
+			j := i
+			turns := 0
+			var last [2]int
+			for j+1 < len(runes) {
+				from, okFrom := k.keys[runes[j]]
+				to, okTo := k.keys[runes[j+1]]
+				if !okFrom || !okTo {
+					break
+				}
+				direction, ok := k.direction(from, to)
+				if !ok {
+					break
+				}
+				if j == i || direction != last {
+					turns++
+				}
+				last = direction
+				j++
+			}
+
+			if j-i >= 2 {
+				shifted := 0
+				for _, r := range runes[i : j+1] {
+					if k.shifted[r] {
+						shifted++
+					}
+				}
+				matches = append(matches, match{
+					pattern: patternSpatial,
+					i:       i,
+					j:       j,
+					token:   string(runes[i : j+1]),
+					guesses: k.walkGuesses(j-i+1, turns, shifted),
+				})
+				i = j
+			} else {
+				i++
+			}
+		}
+	}
+	return matches
+}
+
+func (k *keyboard) walkGuesses(length, turns, shifted int) float64 {
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * k.startingPositions * math.Pow(k.averageDegree, float64(j))
+		}
+	}
+
+	if shifted > 0 {
+		plain := length - shifted
+		if plain == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= min(shifted, plain); i++ {
+				variations += binomial(shifted+plain, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+// repeats and sequences
+
+// repeatMatches finds the longest repetition of a base string starting at each position,
+// like aaa or abcabc
+func repeatMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes); {
+		bestLength, bestBase := 0, 0
+		for base := 1; i+2*base <= len(runes); base++ {
+			count := 1
+			for i+(count+1)*base <= len(runes) && string(runes[i+count*base:i+(count+1)*base]) == string(runes[i:i+base]) {
+				count++
+			}
+			if count > 1 && count*base > bestLength {
+				bestLength, bestBase = count*base, base
+			}
+		}
+
+		if bestLength == 0 {
+			i++
+			continue
+		}
+		j := i + bestLength - 1
+		base := string(runes[i : i+bestBase])
+		matches = append(matches, match{
+			pattern: patternRepeat,
+			i:       i,
+			j:       j,
+			token:   string(runes[i : j+1]),
+			guesses: estimateGuesses(base) * float64(bestLength/bestBase),
+		})
+		i = j + 1
+	}
+	return matches
+}
+
+// sequenceMatches finds runs like abc, 13579 or 9876 where each character moves by the same step
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes)-2; {
+		delta := runes[i+1] - runes[i]
+		if delta == 0 || delta < -5 || delta > 5 || !sameClass(runes[i], runes[i+1]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta && sameClass(runes[j], runes[j+1]) {
+			j++
+		}
+		if j-i < 2 {
+			i++
+			continue
+		}
+
+		first := runes[i]
+		var guesses float64
+		switch {
+		case strings.ContainsRune("aAzZ019", first):
+			guesses = 4
+		case unicode.IsDigit(first):
+			guesses = 10
+		default:
+			guesses = 26
+		}
+		if delta < 0 {
+			guesses *= 2
+		}
+		matches = append(matches, match{pattern: patternSequence, i: i, j: j, token: string(runes[i : j+1]), guesses: guesses * float64(j-i+1)})
+		i = j
+	}
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	switch {
+	case unicode.IsLower(a):
+		return unicode.IsLower(b)
+	case unicode.IsUpper(a):
+		return unicode.IsUpper(b)
+	case unicode.IsDigit(a):
+		return unicode.IsDigit(b)
+	default:
+		return false
+	}
+}
+
+// dates
+
+var dateWithSeparator = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+
+// dateMatches finds years (1984) and dates with or without separators (13/05/1991, 130591, 19910513)
+func dateMatches(runes []rune) []match {
+	now := time.Now().Year()
+	var matches []match
+
+	for i := range runes {
+		for j := i + 3; j < len(runes) && j-i < 10; j++ {
+			token := string(runes[i : j+1])
+
+			if j-i == 3 && isDigits(token) {
+				if year, _ := strconv.Atoi(token); year >= 1900 && year <= 2099 {
+					matches = append(matches, match{pattern: patternDate, i: i, j: j, token: token, guesses: yearSpace(year, now)})
+				}
+			}
+
+			if year, ok := parseDate(token); ok {
+				guesses := 365 * yearSpace(year, now)
+				if !isDigits(token) {
+					guesses *= 4
+				}
+				matches = append(matches, match{pattern: patternDate, i: i, j: j, token: token, guesses: guesses})
+			}
+		}
+	}
+	return matches
+}
+
+func yearSpace(year, now int) float64 {
+	return math.Max(math.Abs(float64(year-now)), minYearSpace)
+}
+
+// parseDate tells if the token is a date in any usual order and returns its year
+func parseDate(token string) (int, bool) {
+	var parts [][3]string
+	if isDigits(token) {
+		if len(token) > 8 {
+			return 0, false
+		}
+		// every split into day, month and year, with the year first or last
+		for a := 1; a < len(token)-1; a++ {
+			for b := a + 1; b < len(token); b++ {
+				parts = append(parts, [3]string{token[:a], token[a:b], token[b:]})
+			}
+		}
+	} else {
+		groups := dateWithSeparator.FindStringSubmatch(token)
+		if groups == nil || groups[2] != groups[4] {
+			return 0, false
+		}
+		parts = append(parts, [3]string{groups[1], groups[3], groups[5]})
+	}
+
+	for _, part := range parts {
+		// the year goes last (13/05/1991, 05/13/1991) or first (1991/05/13)
+		for _, order := range [][3]string{{part[2], part[0], part[1]}, {part[0], part[1], part[2]}} {
+			year, ok := parseYear(order[0])
+			if !ok || len(order[1]) > 2 || len(order[2]) > 2 {
+				continue
+			}
+			a, _ := strconv.Atoi(order[1])
+			b, _ := strconv.Atoi(order[2])
+			if validDayMonth(a, b) || validDayMonth(b, a) {
+				return year, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func parseYear(value string) (int, bool) {
+	year, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	switch len(value) {
+	case 2:
+		// two digit years are read like people write them: 91 is 1991, 05 is 2005
+		if year > 50 {
+			return 1900 + year, true
+		}
+		return 2000 + year, true
+	case 4:
+		return year, year >= 1000 && year <= 2099
+	default:
+		return 0, false
+	}
+}
+
+func validDayMonth(day, month int) bool {
+	return month >= 1 && month <= 12 && day >= 1 && day <= 31
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+	return result
+}
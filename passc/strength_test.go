@@ -0,0 +1,117 @@
+package passc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+		pattern  string
+	}{
+		{"password", 0, 0, patternDictionary},
+		{"P@ssw0rd", 0, 0, patternDictionary},
+		{"drowssap", 0, 0, patternDictionary},
+		{"aaaaaaaa", 0, 0, patternRepeat},
+		{"abcabcabc", 0, 0, patternRepeat},
+		{"abcdefg", 0, 0, patternSequence},
+		{"97531", 0, 0, patternSequence},
+		{"1991", 0, 0, patternDate},
+		{"13/05/1991", 1, 0, patternDate},
+		{"poiuy", 1, 0, patternSpatial},
+		{"poiuylkjh", 2, 0, patternSpatial},
+		{"github", 0, 0, patternDictionary},
+		{"correcthorsebatterystaple", 4, 4, ""},
+		{"Xk9$mQ2vLp3@wR7n", 4, 4, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.password, func(t *testing.T) {
+			strength := estimateStrength(test.password, "github", "john")
+			if strength.Score < test.minScore || strength.Score > test.maxScore {
+				t.Errorf("expected a score between %d and %d, got %d", test.minScore, test.maxScore, strength.Score)
+			}
+			if test.pattern == "" {
+				return
+			}
+
+			runes := []rune(test.password)
+			sequence, _ := mostGuessableSequence(runes, findMatches(runes, []string{"github"}))
+			found := false
+			for _, m := range sequence {
+				found = found || m.pattern == test.pattern
+			}
+			if !found {
+				t.Errorf("expected a %s match, got %+v", test.pattern, sequence)
+			}
+			if strength.Warning == "" {
+				t.Error("expected a warning")
+			}
+		})
+	}
+}
+
+func TestEstimateStrengthOrder(t *testing.T) {
+	// adding randomness never makes a password weaker
+	weaker := estimateStrength("monkey")
+	stronger := estimateStrength("monkey7#Kq")
+	if stronger.Guesses <= weaker.Guesses {
+		t.Errorf("expected %v > %v", stronger.Guesses, weaker.Guesses)
+	}
+	if math.Abs(weaker.Bits()-math.Log2(weaker.Guesses)) > 1e-9 {
+		t.Errorf("unexpected bits %v", weaker.Bits())
+	}
+}
+
+func TestUppercaseVariations(t *testing.T) {
+	tests := map[string]float64{
+		"password": 1,
+		"Password": 2,
+		"passworD": 2,
+		"PASSWORD": 2,
+		"PaSsword": binomial(8, 1) + binomial(8, 2),
+	}
+	for token, expected := range tests {
+		if actual := uppercaseVariations(token); actual != expected {
+			t.Errorf("%s: expected %v, got %v", token, expected, actual)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	valid := map[string]int{
+		"13/05/1991": 1991,
+		"1991-05-13": 1991,
+		"130591":     1991,
+		"19910513":   1991,
+		"5.13.05":    2005,
+	}
+	for token, expected := range valid {
+		if year, ok := parseDate(token); !ok || year != expected {
+			t.Errorf("%s: expected %d, got %d %v", token, expected, year, ok)
+		}
+	}
+	for _, token := range []string{"13/05-1991", "99999999", "1991/13/13"} {
+		if _, ok := parseDate(token); ok {
+			t.Errorf("%s must not be a date", token)
+		}
+	}
+}
+
+func TestDisplayTime(t *testing.T) {
+	tests := map[float64]string{
+		0.5:               "less than a second",
+		1:                 "1 second",
+		90:                "2 minutes",
+		3 * 24 * 3600:     "3 days",
+		200 * 365 * 86400: "centuries",
+	}
+	for seconds, expected := range tests {
+		if actual := displayTime(seconds); actual != expected {
+			t.Errorf("%v: expected %s, got %s", seconds, expected, actual)
+		}
+	}
+}
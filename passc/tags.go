@@ -0,0 +1,95 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var tagError = errors.New(passcTagErr)
+
+func validateTag(tag string) error {
+	if tag == "" || strings.ContainsAny(tag, " \t\n,") {
+		return fmt.Errorf("%w: %q", tagError, tag)
+	}
+	return nil
+}
+
+func (d Data) hasTag(tag string) bool {
+	return slices.Contains(d.Tags, tag)
+}
+
+// hasTags tells if the entry has every tag of the selector. An empty selector matches everything
+func (d Data) hasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !d.hasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// addTags returns how many of the tags were new to the entry
+func (d *Data) addTags(tags ...string) int {
+	added := 0
+	for _, tag := range tags {
+		if !d.hasTag(tag) {
+			d.Tags = append(d.Tags, tag)
+			added++
+		}
+	}
+	return added
+}
+
+// removeTags returns how many of the tags the entry had
+func (d *Data) removeTags(tags ...string) int {
+	before := len(d.Tags)
+	d.Tags = slices.DeleteFunc(d.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	if len(d.Tags) == 0 {
+		d.Tags = nil
+	}
+	return before - len(d.Tags)
+}
+
+// selectByTags keeps the entries having every tag of the selector, used by the bulk commands
+func selectByTags(items []Data, tags []string) []Data {
+	if len(tags) == 0 {
+		return items
+	}
+	var selected []Data
+	for _, d := range items {
+		if d.hasTags(tags) {
+			selected = append(selected, d)
+		}
+	}
+	return selected
+}
+
+type tagCount struct {
+	Tag   string
+	Count int
+}
+
+// countTags returns how many entries have each tag, most used first
+func countTags(items []Data) []tagCount {
+	counts := make(map[string]int)
+	for _, d := range items {
+		for _, tag := range d.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]tagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, tagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
@@ -0,0 +1,55 @@
+package passc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	data := Data{Name: "github"}
+	if added := data.addTags("shared", "client-x", "shared"); added != 2 {
+		t.Errorf("expected 2 new tags, got %d", added)
+	}
+	if !data.hasTags([]string{"client-x", "shared"}) || data.hasTags([]string{"shared", "rotate-q3"}) || !data.hasTags(nil) {
+		t.Errorf("unexpected tag matching for %+v", data.Tags)
+	}
+	if removed := data.removeTags("shared", "rotate-q3"); removed != 1 || !reflect.DeepEqual(data.Tags, []string{"client-x"}) {
+		t.Errorf("expected only client-x left, got %d %+v", removed, data.Tags)
+	}
+	data.removeTags("client-x")
+	if data.Tags != nil {
+		t.Errorf("expected no tags, got %+v", data.Tags)
+	}
+
+	for _, tag := range []string{"", "two words", "a,b"} {
+		if err := validateTag(tag); !errors.Is(err, tagError) {
+			t.Errorf("%q: expected an invalid tag, got %v", tag, err)
+		}
+	}
+}
+
+func TestSelectAndCountTags(t *testing.T) {
+	items := []Data{
+		{Name: "aws", Tags: []string{"client-x", "rotate-q3"}},
+		{Name: "github", Tags: []string{"shared", "client-x"}},
+		{Name: "gitlab", Tags: []string{"shared"}},
+		{Name: "mail"},
+	}
+
+	selected := selectByTags(items, []string{"client-x"})
+	if len(selected) != 2 || selected[0].Name != "aws" || selected[1].Name != "github" {
+		t.Errorf("unexpected selection %+v", selected)
+	}
+	if selected := selectByTags(items, []string{"client-x", "shared"}); len(selected) != 1 || selected[0].Name != "github" {
+		t.Errorf("unexpected selection %+v", selected)
+	}
+	if selected := selectByTags(items, nil); len(selected) != 4 {
+		t.Errorf("an empty selector must keep everything, got %+v", selected)
+	}
+
+	expected := []tagCount{{"client-x", 2}, {"shared", 2}, {"rotate-q3", 1}}
+	if counts := countTags(items); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %+v, got %+v", expected, counts)
+	}
+}
@@ -0,0 +1,394 @@
+package passc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	entryNotFoundError = errors.New("entry not found")
+	entryExistsError   = errors.New("entry already exists")
+)
+
+// Vault stores entries by name. Commands only talk to this interface,
+// so the storage behind them can be swapped (and faked in tests)
+type Vault interface {
+	Get(name string) (Data, error)
+	List() ([]Data, error)
+	// Put inserts the entries or replaces the ones with the same name
+	Put(entries ...Data) error
+	// Delete removes the entry for good, Trash keeps it restorable
+	Delete(name string) error
+	Rename(oldName, newName string) error
+	// Move renames several entries as a single change. A target may be the name another moved
+	// entry leaves, nothing is renamed when any of them fails
+	Move(moves map[string]string) error
+
+	Trash(name string) error
+	Trashed() ([]TrashedEntry, error)
+	// Restore brings back the most recently trashed entry with that name
+	Restore(name string) error
+	// Purge deletes for good the entries trashed before the given time and returns how many
+	Purge(before time.Time) (int, error)
+}
+
+// openVault is replaced in tests to run commands without touching $HOME
+var openVault = openFileVault
+
+// entries holds the shared logic of the Vault implementations over a plain slice
+type entries []Data
+
+func (e entries) index(name string) int {
+	for i, d := range e {
+		if d.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e entries) get(name string) (Data, error) {
+	if i := e.index(name); i != -1 {
+		return e[i], nil
+	}
+	return Data{}, fmt.Errorf("%w: %s", entryNotFoundError, name)
+}
+
+func (e entries) put(items ...Data) entries {
+	for _, item := range items {
+		if i := e.index(item.Name); i != -1 {
+			e[i] = item
+		} else {
+			e = append(e, item)
+		}
+	}
+	return e
+}
+
+func (e entries) delete(name string) (entries, error) {
+	i := e.index(name)
+	if i == -1 {
+		return e, fmt.Errorf("%w: %s", entryNotFoundError, name)
+	}
+	return append(e[:i], e[i+1:]...), nil
+}
+
+func (e entries) rename(oldName, newName string) (entries, error) {
+	i := e.index(oldName)
+	if i == -1 {
+		return e, fmt.Errorf("%w: %s", entryNotFoundError, oldName)
+	}
+	if oldName != newName && e.index(newName) != -1 {
+		return e, fmt.Errorf("%w: %s", entryExistsError, newName)
+	}
+	e[i].Name = newName
+	return e, nil
+}
+
+func (e entries) move(moves map[string]string) (entries, error) {
+	targets := make(map[string]bool, len(moves))
+	for oldName, newName := range moves {
+		if e.index(oldName) == -1 {
+			return e, fmt.Errorf("%w: %s", entryNotFoundError, oldName)
+		}
+		if targets[newName] {
+			return e, fmt.Errorf("%w: %s", entryExistsError, newName)
+		}
+		targets[newName] = true
+	}
+	for _, d := range e {
+		if _, moving := moves[d.Name]; !moving && targets[d.Name] {
+			return e, fmt.Errorf("%w: %s", entryExistsError, d.Name)
+		}
+	}
+
+	for i := range e {
+		if newName, ok := moves[e[i].Name]; ok {
+			e[i].Name = newName
+		}
+	}
+	return e, nil
+}
+
+// trashEntry moves the entry from the vault to its trash
+func (d *vaultDocument) trashEntry(name string, now time.Time) error {
+	items := entries(d.Entries)
+	data, err := items.get(name)
+	if err != nil {
+		return err
+	}
+	if items, err = items.delete(name); err != nil {
+		return err
+	}
+	d.Entries = items
+	d.Trash = append(d.Trash, TrashedEntry{Data: data, DeletedAt: now})
+	return nil
+}
+
+func (d *vaultDocument) restoreEntry(name string) error {
+	last := -1
+	for i, trashed := range d.Trash {
+		if trashed.Name == name && (last == -1 || !trashed.DeletedAt.Before(d.Trash[last].DeletedAt)) {
+			last = i
+		}
+	}
+	if last == -1 {
+		return fmt.Errorf("%w: %s", entryNotFoundError, name)
+	}
+	if entries(d.Entries).index(name) != -1 {
+		return fmt.Errorf("%w: %s", entryExistsError, name)
+	}
+
+	d.Entries = append(d.Entries, d.Trash[last].Data)
+	d.Trash = append(d.Trash[:last], d.Trash[last+1:]...)
+	return nil
+}
+
+func (d *vaultDocument) purgeTrash(before time.Time) int {
+	var kept []TrashedEntry
+	for _, trashed := range d.Trash {
+		if !trashed.DeletedAt.Before(before) {
+			kept = append(kept, trashed)
+		}
+	}
+	purged := len(d.Trash) - len(kept)
+	d.Trash = kept
+	return purged
+}
+
+// fileVault keeps every entry in the encrypted store file and rewrites it on each change
+type fileVault struct {
+	encryptor *Encryptor
+	retention BackupConfig
+	lock      *storeLock
+}
+
+func openFileVault() (Vault, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	encryptor, err := checkMasterPassword()
+	if err != nil {
+		return nil, err
+	}
+	return &fileVault{encryptor: encryptor, retention: config.Backup}, nil
+}
+
+// openUnlockedVault opens the vault only when the agent holds its key, so it never prompts
+var openUnlockedVault = openUnlockedFileVault
+
+func openUnlockedFileVault() (Vault, error) {
+	filePath, err := storeFilePath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	agentKey := getAgentKey(filePath)
+	if !agentKey.IsValue() {
+		return nil, invalidPasswordError
+	}
+	encryptor := &Encryptor{FilePath: filePath, KDF: config.KDF, key: agentKey.AsPtr()}
+	if _, err := encryptor.unlock(); err != nil {
+		return nil, err
+	}
+	return &fileVault{encryptor: encryptor, retention: config.Backup}, nil
+}
+
+func (v *fileVault) storeLock() *storeLock {
+	if v.lock == nil {
+		v.lock = newStoreLock(v.encryptor.FilePath)
+	}
+	return v.lock
+}
+
+func (v *fileVault) Lock() error {
+	return v.storeLock().Lock()
+}
+
+func (v *fileVault) Unlock() error {
+	return v.storeLock().Unlock()
+}
+
+func (v *fileVault) load() (*vaultDocument, error) {
+	content, err := v.encryptor.readEncryptedText()
+	if err != nil {
+		if errors.Is(err, emptyFile) {
+			return &vaultDocument{Entries: []Data{}}, nil
+		}
+		return nil, err
+	}
+	document, err := decodeDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (v *fileVault) save(document *vaultDocument) error {
+	if len(document.Entries) == 0 && len(document.Trash) == 0 {
+		if err := v.encryptor.deleteContent(); err != nil {
+			return fmt.Errorf("deleting file: %v", err)
+		}
+	} else {
+		content, err := encodeDocument(*document)
+		if err != nil {
+			return fmt.Errorf("converting data to JSON: %v", err)
+		}
+		if err := v.encryptor.encryptText(content); err != nil {
+			return fmt.Errorf("encrypting text: %v", err)
+		}
+	}
+	if _, err := makeBackUp(v.encryptor.FilePath, v.retention); err != nil && !errors.Is(err, emptyFile) {
+		return fmt.Errorf("vault saved but backing it up failed: %v", err)
+	}
+	return nil
+}
+
+// update runs a read-modify-write of the whole document under the store lock
+func (v *fileVault) update(change func(document *vaultDocument) error) error {
+	if err := v.Lock(); err != nil {
+		return err
+	}
+	defer v.Unlock()
+
+	document, err := v.load()
+	if err != nil {
+		return err
+	}
+	if err := change(document); err != nil {
+		return err
+	}
+	return v.save(document)
+}
+
+func (v *fileVault) Get(name string) (Data, error) {
+	document, err := v.load()
+	if err != nil {
+		return Data{}, err
+	}
+	return entries(document.Entries).get(name)
+}
+
+func (v *fileVault) List() ([]Data, error) {
+	document, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+	return document.Entries, nil
+}
+
+func (v *fileVault) Put(items ...Data) error {
+	return v.update(func(document *vaultDocument) error {
+		document.Entries = entries(document.Entries).put(items...)
+		return nil
+	})
+}
+
+func (v *fileVault) Delete(name string) error {
+	return v.update(func(document *vaultDocument) (err error) {
+		document.Entries, err = entries(document.Entries).delete(name)
+		return err
+	})
+}
+
+func (v *fileVault) Rename(oldName, newName string) error {
+	return v.update(func(document *vaultDocument) (err error) {
+		document.Entries, err = entries(document.Entries).rename(oldName, newName)
+		return err
+	})
+}
+
+func (v *fileVault) Move(moves map[string]string) error {
+	return v.update(func(document *vaultDocument) (err error) {
+		document.Entries, err = entries(document.Entries).move(moves)
+		return err
+	})
+}
+
+func (v *fileVault) Trash(name string) error {
+	return v.update(func(document *vaultDocument) error {
+		return document.trashEntry(name, time.Now())
+	})
+}
+
+func (v *fileVault) Trashed() ([]TrashedEntry, error) {
+	document, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+	return document.Trash, nil
+}
+
+func (v *fileVault) Restore(name string) error {
+	return v.update(func(document *vaultDocument) error {
+		return document.restoreEntry(name)
+	})
+}
+
+func (v *fileVault) Purge(before time.Time) (int, error) {
+	var purged int
+	err := v.update(func(document *vaultDocument) error {
+		purged = document.purgeTrash(before)
+		return nil
+	})
+	return purged, err
+}
+
+// memoryVault is a Vault that lives only in memory
+type memoryVault struct {
+	document vaultDocument
+}
+
+func newMemoryVault(items ...Data) *memoryVault {
+	return &memoryVault{document: vaultDocument{Entries: entries{}.put(items...)}}
+}
+
+func (v *memoryVault) Get(name string) (Data, error) {
+	return entries(v.document.Entries).get(name)
+}
+
+func (v *memoryVault) List() ([]Data, error) {
+	return append([]Data{}, v.document.Entries...), nil
+}
+
+func (v *memoryVault) Put(items ...Data) error {
+	v.document.Entries = entries(v.document.Entries).put(items...)
+	return nil
+}
+
+func (v *memoryVault) Delete(name string) (err error) {
+	v.document.Entries, err = entries(v.document.Entries).delete(name)
+	return err
+}
+
+func (v *memoryVault) Rename(oldName, newName string) (err error) {
+	v.document.Entries, err = entries(v.document.Entries).rename(oldName, newName)
+	return err
+}
+
+func (v *memoryVault) Move(moves map[string]string) (err error) {
+	v.document.Entries, err = entries(v.document.Entries).move(moves)
+	return err
+}
+
+func (v *memoryVault) Trash(name string) error {
+	return v.document.trashEntry(name, time.Now())
+}
+
+func (v *memoryVault) Trashed() ([]TrashedEntry, error) {
+	return append([]TrashedEntry{}, v.document.Trash...), nil
+}
+
+func (v *memoryVault) Restore(name string) error {
+	return v.document.restoreEntry(name)
+}
+
+func (v *memoryVault) Purge(before time.Time) (int, error) {
+	return v.document.purgeTrash(before), nil
+}
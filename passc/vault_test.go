@@ -0,0 +1,195 @@
+package passc
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVaultImplementations(t *testing.T) {
+	vaults := map[string]func(t *testing.T) Vault{
+		"memory": func(t *testing.T) Vault { return newMemoryVault() },
+		"file": func(t *testing.T) Vault {
+			filePath := filepath.Join(t.TempDir(), passcStoreFile)
+			return &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}}
+		},
+	}
+
+	for name, newVault := range vaults {
+		t.Run(name, func(t *testing.T) {
+			vault := newVault(t)
+
+			items, err := vault.List()
+			if err != nil || len(items) != 0 {
+				t.Fatalf("expected an empty vault, got %v %v", items, err)
+			}
+
+			john := Data{Name: "john", Password: "1234"}
+			jane := Data{Name: "jane", Password: "5678", Info: "info"}
+			if err := vault.Put(john, jane); err != nil {
+				t.Fatal(err)
+			}
+
+			john.Password = "4321"
+			if err := vault.Put(john); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := vault.Get("john"); err != nil || !reflect.DeepEqual(got, john) {
+				t.Errorf("expected %+v, got %+v %v", john, got, err)
+			}
+
+			if _, err := vault.Get("bob"); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("expected not found, got %v", err)
+			}
+
+			if err := vault.Rename("john", "jane"); !errors.Is(err, entryExistsError) {
+				t.Errorf("expected entry exists, got %v", err)
+			}
+			if err := vault.Rename("john", "johnny"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := vault.Get("john"); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("renamed entry must not be found by its old name, got %v", err)
+			}
+
+			if err := vault.Move(map[string]string{"johnny": "jane"}); !errors.Is(err, entryExistsError) {
+				t.Errorf("expected entry exists, got %v", err)
+			}
+			if err := vault.Move(map[string]string{"johnny": "x", "bob": "y"}); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("expected not found, got %v", err)
+			}
+			if _, err := vault.Get("johnny"); err != nil {
+				t.Errorf("a failed move must rename nothing, got %v", err)
+			}
+			// targets may be the names other moved entries leave
+			if err := vault.Move(map[string]string{"johnny": "jane", "jane": "johnny"}); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := vault.Get("jane"); err != nil || got.Password != "4321" {
+				t.Errorf("expected the entries swapped, got %+v %v", got, err)
+			}
+			if err := vault.Move(map[string]string{"johnny": "jane", "jane": "johnny"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := vault.Delete("bob"); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("expected not found, got %v", err)
+			}
+			if err := vault.Delete("jane"); err != nil {
+				t.Fatal(err)
+			}
+
+			items, err = vault.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := []Data{{Name: "johnny", Password: "4321"}}
+			if !reflect.DeepEqual(items, expected) {
+				t.Errorf("expected %+v, got %+v", expected, items)
+			}
+
+			if err := vault.Delete("johnny"); err != nil {
+				t.Fatal(err)
+			}
+			if items, err := vault.List(); err != nil || len(items) != 0 {
+				t.Errorf("expected an empty vault, got %v %v", items, err)
+			}
+		})
+	}
+}
+
+func TestFileVaultAwkwardValues(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), passcStoreFile)
+	encryptor := &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath, KDF: testKDFParams}
+	vault := &fileVault{encryptor: encryptor}
+
+	other := Data{Name: `"other"`, Password: "|", Info: "|"}
+	if err := vault.Put(awkward, other); err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh vault over the same file only sees what was decrypted and decoded
+	reopened := &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filePath}}
+	if got, err := reopened.Get(awkward.Name); err != nil || !reflect.DeepEqual(got, awkward) {
+		t.Errorf("expected %+v, got %+v %v", awkward, got, err)
+	}
+	if err := reopened.Rename(other.Name, `o|ther "renamed"`); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Delete(awkward.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := vault.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Data{{Name: `o|ther "renamed"`, Password: "|", Info: "|"}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %+v, got %+v", expected, items)
+	}
+}
+
+func TestVaultTrash(t *testing.T) {
+	vaults := map[string]Vault{
+		"memory": newMemoryVault(),
+		"file":   &fileVault{encryptor: &Encryptor{MasterPassword: "p4$$w0rd", FilePath: filepath.Join(t.TempDir(), passcStoreFile), KDF: testKDFParams}},
+	}
+
+	for name, vault := range vaults {
+		t.Run(name, func(t *testing.T) {
+			first := Data{Name: "john", Password: "first"}
+			if err := vault.Put(first, Data{Name: "jane"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := vault.Trash("john"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := vault.Get("john"); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("a trashed entry must leave the vault, got %v", err)
+			}
+			if err := vault.Trash("john"); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("expected not found, got %v", err)
+			}
+
+			second := Data{Name: "john", Password: "second"}
+			if err := vault.Put(second); err != nil {
+				t.Fatal(err)
+			}
+			if err := vault.Restore("john"); !errors.Is(err, entryExistsError) {
+				t.Errorf("restoring over an existing entry must fail, got %v", err)
+			}
+			time.Sleep(time.Millisecond)
+			if err := vault.Trash("john"); err != nil {
+				t.Fatal(err)
+			}
+
+			trashed, err := vault.Trashed()
+			if err != nil || len(trashed) != 2 {
+				t.Fatalf("expected 2 trashed entries, got %+v %v", trashed, err)
+			}
+
+			if err := vault.Restore("john"); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := vault.Get("john"); err != nil || !reflect.DeepEqual(got, second) {
+				t.Errorf("expected the latest removal %+v back, got %+v %v", second, got, err)
+			}
+			if err := vault.Restore("bob"); !errors.Is(err, entryNotFoundError) {
+				t.Errorf("expected not found, got %v", err)
+			}
+
+			if purged, err := vault.Purge(trashed[0].DeletedAt); err != nil || purged != 0 {
+				t.Errorf("nothing was trashed before the first removal, got %d %v", purged, err)
+			}
+			if purged, err := vault.Purge(time.Now().Add(time.Second)); err != nil || purged != 1 {
+				t.Errorf("expected 1 purged entry, got %d %v", purged, err)
+			}
+			if trashed, err := vault.Trashed(); err != nil || len(trashed) != 0 {
+				t.Errorf("expected an empty trash, got %+v %v", trashed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package passc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const defaultVaultName = "default"
+
+var (
+	// storeFlag holds the global --store flag: a vault name or a store file path
+	storeFlag string
+
+	vaultNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+	vaultNotFoundError = errors.New(passcVaultNotFoundErr)
+	vaultExistsError   = errors.New(passcVaultExistsErr)
+	vaultNameError     = errors.New(passcVaultNameErr)
+)
+
+func passcDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding user home dir: %v", err)
+	}
+	return filepath.Join(homeDir, passcDirFolder), nil
+}
+
+// storeSelection picks the vault in order: --store flag, PASSC_STORE env var, `passc vault use`
+func storeSelection() (string, error) {
+	if storeFlag != "" {
+		return storeFlag, nil
+	}
+	if env := os.Getenv(passcStoreEnv); env != "" {
+		return env, nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return config.Vault, nil
+}
+
+func storeFilePath() (string, error) {
+	selection, err := storeSelection()
+	if err != nil {
+		return "", err
+	}
+	return resolveStore(selection)
+}
+
+// isStorePath tells file paths apart from vault names
+func isStorePath(selection string) bool {
+	return strings.ContainsRune(selection, filepath.Separator) || filepath.Ext(selection) == filepath.Ext(passcStoreFile)
+}
+
+func resolveStore(selection string) (string, error) {
+	if isStorePath(selection) {
+		if strings.HasPrefix(selection, "~/") {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("finding user home dir: %v", err)
+			}
+			selection = filepath.Join(homeDir, selection[2:])
+		}
+		return filepath.Abs(selection)
+	}
+
+	dir, err := passcDir()
+	if err != nil {
+		return "", err
+	}
+
+	if selection == "" || selection == defaultVaultName {
+		return filepath.Join(dir, passcStoreFile), nil
+	}
+
+	vaultDir, err := vaultDir(selection)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(vaultDir); err != nil {
+		return "", fmt.Errorf("%w: %s", vaultNotFoundError, selection)
+	}
+	return filepath.Join(vaultDir, passcStoreFile), nil
+}
+
+func vaultDir(name string) (string, error) {
+	if !vaultNamePattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %s", vaultNameError, name)
+	}
+
+	dir, err := passcDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, passcVaultsFolder, name), nil
+}
+
+// backupDir keeps the backup generations next to their store: store.passc -> store.backups/
+func backupDir(filePath string) string {
+	base := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	return filepath.Join(filepath.Dir(filePath), base+passcBackupsExtension)
+}
+
+// legacyBackupFilePath is the single backup written by older versions: store.passc -> store.bkp
+func legacyBackupFilePath(filePath string) string {
+	base := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	return filepath.Join(filepath.Dir(filePath), base+filepath.Ext(passcBackUpFile))
+}
+
+// listVaults returns the default vault followed by the named ones, sorted
+func listVaults() ([]string, error) {
+	dir, err := passcDir()
+	if err != nil {
+		return nil, err
+	}
+
+	vaults := []string{defaultVaultName}
+	entries, err := os.ReadDir(filepath.Join(dir, passcVaultsFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vaults, nil
+		}
+		return nil, fmt.Errorf("reading vaults: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && vaultNamePattern.MatchString(entry.Name()) && entry.Name() != defaultVaultName {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return append(vaults, names...), nil
+}
+
+// createVault makes the private vault directory. The master password is set on its first use
+func createVault(name string) (string, error) {
+	if name == defaultVaultName {
+		return "", fmt.Errorf("%w: %s", vaultExistsError, name)
+	}
+
+	dir, err := vaultDir(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return "", fmt.Errorf("creating vaults directory: %v", err)
+	}
+	if err := os.Mkdir(dir, 0700); err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("%w: %s", vaultExistsError, name)
+		}
+		return "", fmt.Errorf("creating vault: %v", err)
+	}
+	return filepath.Join(dir, passcStoreFile), nil
+}
+
+// useVault stores the selected vault in config.json keeping every other setting untouched
+func useVault(name string) error {
+	if name != defaultVaultName {
+		if _, err := resolveStore(name); err != nil {
+			return err
+		}
+	}
+
+	dir, err := passcDir()
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(dir, passcConfigFile)
+
+	settings := make(map[string]json.RawMessage)
+	content, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading config: %v", err)
+	}
+	if len(content) != 0 {
+		if err := json.Unmarshal(content, &settings); err != nil {
+			return fmt.Errorf("parsing config: %v", err)
+		}
+	}
+
+	if name == defaultVaultName {
+		delete(settings, "vault")
+	} else {
+		settings["vault"], _ = json.Marshal(name)
+	}
+
+	content, err = json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating directory: %v", err)
+	}
+	return writeFileAtomic(configPath, append(content, '\n'), 0644)
+}
@@ -0,0 +1,133 @@
+package passc
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStoreSelection(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(passcStoreEnv, "")
+	storeFlag = ""
+	t.Cleanup(func() { storeFlag = "" })
+
+	defaultStore := filepath.Join(home, passcDirFolder, passcStoreFile)
+	workStore := filepath.Join(home, passcDirFolder, passcVaultsFolder, "work", passcStoreFile)
+
+	expectStore := func(expected string) {
+		t.Helper()
+		actual, err := storeFilePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("expected %s, got %s", expected, actual)
+		}
+	}
+
+	expectStore(defaultStore)
+
+	if _, err := resolveStore("work"); !errors.Is(err, vaultNotFoundError) {
+		t.Errorf("expected vault not found, got %v", err)
+	}
+	if _, err := createVault("work"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createVault("work"); !errors.Is(err, vaultExistsError) {
+		t.Errorf("expected vault exists, got %v", err)
+	}
+	if _, err := createVault("../escape"); err == nil {
+		t.Error("vault names must not contain paths")
+	}
+	if _, err := createVault("personal"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := useVault("work"); err != nil {
+		t.Fatal(err)
+	}
+	expectStore(workStore)
+
+	t.Setenv(passcStoreEnv, "personal")
+	expectStore(filepath.Join(home, passcDirFolder, passcVaultsFolder, "personal", passcStoreFile))
+
+	storeFlag = "~/customer.passc"
+	expectStore(filepath.Join(home, "customer.passc"))
+
+	storeFlag = ""
+	t.Setenv(passcStoreEnv, "")
+	if err := useVault(defaultVaultName); err != nil {
+		t.Fatal(err)
+	}
+	expectStore(defaultStore)
+
+	vaults, err := listVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vaults, []string{defaultVaultName, "personal", "work"}) {
+		t.Errorf("unexpected vaults %v", vaults)
+	}
+}
+
+func TestUseVaultKeepsConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, passcDirFolder)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, passcConfigFile)
+	if err := os.WriteFile(configPath, []byte(`{"session":{"idleTimeout":"5m"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := createVault("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := useVault("work"); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Vault != "work" || config.Session.IdleTimeout != Duration(5*time.Minute) {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(content, &settings); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := settings["kdf"]; ok {
+		t.Error("defaults must not be written to the config file")
+	}
+}
+
+func TestBackupDir(t *testing.T) {
+	tests := map[string][2]string{
+		"/home/user/.passcualito/store.passc":             {"/home/user/.passcualito/store.backups", "/home/user/.passcualito/store.bkp"},
+		"/home/user/.passcualito/vaults/work/store.passc": {"/home/user/.passcualito/vaults/work/store.backups", "/home/user/.passcualito/vaults/work/store.bkp"},
+		"/data/customer.passc":                            {"/data/customer.backups", "/data/customer.bkp"},
+	}
+	for store, expected := range tests {
+		if actual := backupDir(store); actual != expected[0] {
+			t.Errorf("%s: expected %s, got %s", store, expected[0], actual)
+		}
+		if actual := legacyBackupFilePath(store); actual != expected[1] {
+			t.Errorf("%s: expected %s, got %s", store, expected[1], actual)
+		}
+	}
+}